@@ -0,0 +1,45 @@
+//go:build !race
+
+package logger_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+// The race detector randomly drops the pooled buffers so this test is skipped with it.
+
+func TestSlogTextAllocs(t *testing.T) {
+	l := slog.New(logger.NewSlogTextHandler(io.Discard, &logger.SlogTextOption{
+		ForceFormatting: true,
+		ForceColors:     true,
+		FullTimestamp:   true,
+	})).With(logger.KeyPrefix, "[prefix]").With("f1", 42, "f2", "42")
+
+	attrs := []slog.Attr{slog.Int("f3", 42), slog.String("f4", "4 2"), slog.Bool("f5", true), slog.Duration("f1", time.Second)}
+	allocs := testing.AllocsPerRun(100, func() {
+		l.LogAttrs(context.Background(), slog.LevelInfo, "message", attrs...)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs per record, expect 0", allocs)
+	}
+}
+
+func TestSlogGELFAllocs(t *testing.T) {
+	l := slog.New(logger.NewSlogGELFHandler(io.Discard, &logger.SlogGELFOption{
+		Hostname: "hostname",
+	})).With(logger.KeyPrefix, "[prefix]").With("f1", 42, "f2", "42")
+
+	attrs := []slog.Attr{slog.Int("f3", 42), slog.String("f4", "4 2"), slog.Bool("f5", true), slog.Float64("f6", 1.5)}
+	allocs := testing.AllocsPerRun(100, func() {
+		l.LogAttrs(context.Background(), slog.LevelInfo, "message", attrs...)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs per record, expect 0", allocs)
+	}
+}
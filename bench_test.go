@@ -2,9 +2,13 @@ package logger_test
 
 import (
 	"bytes"
+	"io"
 	"log/slog"
+	"net"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mdouchement/logger"
 	"github.com/sirupsen/logrus"
@@ -34,20 +38,18 @@ func BenchmarkLogrusGELF(b *testing.B) {
 }
 
 func BenchmarkSlogGELF(b *testing.B) {
-	w := new(bytes.Buffer)
-	ll := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname"}))
-	base := logger.WrapSlog(ll)
+	// The loggers are built once so only the records are measured.
+	l := logger.WrapSlog(slog.New(logger.NewSlogGELFHandler(io.Discard, &logger.SlogGELFOption{Hostname: "hostname"})))
+	l = l.WithPrefix("[prefix]").WithField("f1", 42).WithField("f2", "42").WithField("f3", 42).WithField("f4", "42")
+	l = l.WithPrefixf("[%s]", 4242)
+	sl := slog.New(logger.NewSlogGELFHandler(io.Discard, &logger.SlogGELFOption{Hostname: "hostname"})).With("f1", 42, "f2", "42")
 
-	var l logger.Logger
+	b.ReportAllocs()
+	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		l = base.WithPrefix("[prefix]")
-		l = l.WithField("f1", 42).WithField("f2", "42").WithField("f3", 42).WithField("f4", "42")
-		l = l.WithPrefixf("[%s]", 4242)
 		l.Info("message")
+		sl.Info("message", "f3", 42, "f4", "4 2", "f5", time.Second)
 	}
-
-	b.ReportAllocs()
-	result = w.Bytes()
 }
 
 func BenchmarkLogrusText(b *testing.B) {
@@ -77,25 +79,106 @@ func BenchmarkLogrusText(b *testing.B) {
 }
 
 func BenchmarkSlogText(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opt  *logger.SlogTextOption
+	}{
+		{
+			name: "formatted",
+			opt: &logger.SlogTextOption{
+				ForceColors:     true,
+				ForceFormatting: true,
+				PrefixRE:        regexp.MustCompile(`^(\[.*?\])\s`),
+				FullTimestamp:   true,
+				TimestampFormat: "2006-01-02 15:04:05",
+			},
+		},
+		{name: "plain", opt: &logger.SlogTextOption{}},
+		{name: "strict", opt: &logger.SlogTextOption{StrictLogfmt: true}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			// The loggers are built once so only the records are measured.
+			l := logger.WrapSlog(slog.New(logger.NewSlogTextHandler(io.Discard, bc.opt)))
+			l = l.WithPrefix("[prefix]").WithField("f1", 42).WithField("f2", "42").WithField("f3", 42).WithField("f4", "42")
+			l = l.WithPrefixf("[%s]", 4242)
+			sl := slog.New(logger.NewSlogTextHandler(io.Discard, bc.opt)).With("f1", 42, "f2", "42")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				l.Info("message")
+				sl.Info("message", "f3", 42, "f4", "4 2", "f5", time.Second)
+			}
+		})
+	}
+}
+
+func BenchmarkGELFUDPWriter(b *testing.B) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
 	w := new(bytes.Buffer)
-	ll := slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
-		DisableColors:   false,
-		ForceColors:     true,
-		ForceFormatting: true,
-		PrefixRE:        regexp.MustCompile(`^(\[.*?\])\s`),
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	}))
-	base := logger.WrapSlog(ll)
+	slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname"})).Info(
+		strings.Repeat("a verbose message ", 50), "f1", 42, "f2", "42", "stacktrace", strings.Repeat("at main.main() main.go:42\n", 20),
+	)
+	payload := w.Bytes()
+
+	for _, tc := range []struct {
+		name        string
+		compression logger.GELFCompression
+	}{
+		{name: "none", compression: logger.GELFCompressionNone},
+		{name: "gzip", compression: logger.GELFCompressionGzip},
+		{name: "zlib", compression: logger.GELFCompressionZlib},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{Compression: tc.compression})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer w.Close()
+
+			b.SetBytes(int64(len(payload)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := w.Write(payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
 
-	var l logger.Logger
-	for i := 0; i < b.N; i++ {
-		l = base.WithPrefix("[prefix]")
-		l = l.WithField("f1", 42).WithField("f2", "42").WithField("f3", 42).WithField("f4", "42")
-		l = l.WithPrefixf("[%s]", 4242)
-		l.Info("message")
+func BenchmarkBufferGELF(b *testing.B) {
+	build := func() *logger.BufferGELF {
+		gelf := logger.NewBufferGELF()
+		gelf.Add("int", 42)
+		gelf.Add("uint", uint16(42))
+		gelf.Add("float", 4.2)
+		gelf.Add("string", "42")
+		gelf.Host("hostname")
+		gelf.Timestamp(logger.DeterministicTime)
+		gelf.Level(6)
+		gelf.Message("message")
+		result = gelf.Complete(true)
+		return gelf
 	}
 
-	b.ReportAllocs()
-	result = w.Bytes()
+	b.Run("released", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			build().Release()
+		}
+	})
+
+	b.Run("garbage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			build()
+		}
+	})
 }
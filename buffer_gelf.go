@@ -2,9 +2,14 @@ package logger
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 )
@@ -12,15 +17,73 @@ import (
 // A BufferGELF is a buffer used to build GELF payload.
 type BufferGELF struct {
 	buf *bytes.Buffer
+
+	// The additional fields are only tracked when a DuplicateKeys policy is set.
+	dedupe     bool
+	duplicates DuplicateKeys
+	fields     []gelfField
+	scratch    []byte
+
+	invalidNames InvalidFieldNames
+	report       func(err error)
+	encoding     GELFEncoding
+
+	// The messages and the additional fields are tracked when a Payload limit is set.
+	limits    GELFLimits
+	short     gelfField
+	full      gelfField
+	truncated []string
 }
 
-// NewBufferGELF returns a new BufferGELF.
+// The pooled buffers bigger than that are dropped so a huge record doesn't retain memory.
+const maxPooledGELFBuffer = 64 << 10
+
+var gelfPool = sync.Pool{
+	New: func() any {
+		return &BufferGELF{
+			buf: bytes.NewBuffer(make([]byte, 0, 1024)),
+		}
+	},
+}
+
+// NewBufferGELF returns a BufferGELF from a pool.
+// It can be put back in the pool with Release once its payload is no longer used.
 func NewBufferGELF() *BufferGELF {
-	buf := bytes.NewBuffer(make([]byte, 0, 256))
-	buf.WriteString(`{"version":"1.1"`)
-	return &BufferGELF{
-		buf: buf,
+	b := gelfPool.Get().(*BufferGELF)
+	b.buf.WriteString(`{"version":"1.1"`)
+	return b
+}
+
+// Reset discards the payload, the options (e.g. SetDuplicateKeys) are kept.
+func (b *BufferGELF) Reset() {
+	clear(b.fields)
+	b.fields = b.fields[:0]
+	b.short, b.full = gelfField{}, gelfField{}
+	clear(b.truncated)
+	b.truncated = b.truncated[:0]
+	b.buf.Reset()
+	b.buf.WriteString(`{"version":"1.1"`)
+}
+
+// Release puts back the buffer in the pool, neither it nor its payload can be used afterwards.
+func (b *BufferGELF) Release() {
+	if b.buf.Cap() > maxPooledGELFBuffer {
+		return
 	}
+
+	clear(b.fields)
+	b.fields = b.fields[:0]
+	b.dedupe = false
+	b.duplicates = DuplicateKeysLastWins
+	b.invalidNames = InvalidFieldNamesReplace
+	b.report = nil
+	b.encoding = GELFEncoding{}
+	b.limits = GELFLimits{}
+	b.short, b.full = gelfField{}, gelfField{}
+	clear(b.truncated)
+	b.truncated = b.truncated[:0]
+	b.buf.Reset()
+	gelfPool.Put(b)
 }
 
 // Host adds the host to the GELF buffer.
@@ -32,7 +95,7 @@ func (b *BufferGELF) Host(h string) {
 // Level adds the level to the GELF buffer.
 func (b *BufferGELF) Level(l int32) {
 	b.key("level")
-	b.buf.WriteString(fmt.Sprint(l))
+	b.int(int64(l))
 }
 
 // Message adds the short_message/full_message to the GELF buffer.
@@ -42,21 +105,33 @@ func (b *BufferGELF) Message(m string) {
 		// for the short_message and set the full_message to the
 		// original input. If the input has no newlines, stick the
 		// whole thing in short_message.
-		b.key("short_message")
-		b.string(m[:i], true)
-		b.key("full_message")
-		b.string(m, true)
+		b.short = b.messageField("short_message", m[:i], b.limits.ShortMessage)
+		b.full = b.messageField("full_message", m, b.limits.FullMessage)
 		return
 	}
 
-	b.key("short_message")
+	b.short = b.messageField("short_message", m, b.limits.ShortMessage)
+}
+
+// messageField writes the message field truncated to max bytes when max is positive.
+func (b *BufferGELF) messageField(k, m string, max int) gelfField {
+	f := gelfField{key: k, start: b.buf.Len()}
+	b.key(k)
+	f.value = b.buf.Len()
 	b.string(m, true)
+	f.end = b.buf.Len()
+
+	if max > 0 {
+		b.cut(&f, max, "")
+	}
+	return f
 }
 
 // Timestamp adds the timestamp to the GELF buffer.
 func (b *BufferGELF) Timestamp(t time.Time) {
 	b.key("timestamp")
-	b.buf.WriteString(strconv.FormatFloat(
+	b.buf.Write(strconv.AppendFloat(
+		b.buf.AvailableBuffer(),
 		float64(t.UnixNano())/1e9, // Unix epoch timestamp in seconds
 		'f',
 		-1,
@@ -64,41 +139,179 @@ func (b *BufferGELF) Timestamp(t time.Time) {
 	))
 }
 
+// message adds the short_message/full_message of the message prefixed by the given prefix and a space.
+// It is the same as Message(prefix + " " + m) without concatenating the strings.
+func (b *BufferGELF) message(prefix, m string) {
+	if prefix == "" {
+		b.Message(m)
+		return
+	}
+	if b.limits != (GELFLimits{}) {
+		b.Message(prefix + " " + m)
+		return
+	}
+
+	i := strings.IndexByte(prefix, '\n')
+	if i < 0 {
+		if j := strings.IndexByte(m, '\n'); j >= 0 {
+			i = len(prefix) + 1 + j
+		}
+	}
+
+	b.key("short_message")
+	b.buf.WriteByte('"')
+	switch {
+	case i <= 0:
+		b.content(prefix, true)
+		b.buf.WriteByte(' ')
+		b.content(m, true)
+	case i < len(prefix):
+		b.content(prefix[:i], true)
+	default:
+		b.content(prefix, true)
+		b.buf.WriteByte(' ')
+		b.content(m[:i-len(prefix)-1], true)
+	}
+	b.buf.WriteByte('"')
+
+	if i > 0 {
+		b.key("full_message")
+		b.buf.WriteByte('"')
+		b.content(prefix, true)
+		b.buf.WriteByte(' ')
+		b.content(m, true)
+		b.buf.WriteByte('"')
+	}
+}
+
 // Add adds any key/value to the GELF buffer.
+// The fields whose names are reserved by Graylog (e.g. `id', `source' or `gl2_*') are skipped
+// and the invalid names are handled by the InvalidFieldNames policy.
 func (b *BufferGELF) Add(k string, v any) {
-	// skip id
-	if k == "id" || k == "_id" {
+	if b.encoding.Flatten && b.flatten(k, v) {
 		return
 	}
+	b.add(k, func() { b.value(v) })
+}
 
-	b.key("_" + k)
+// addField implements fieldSink.
+func (b *BufferGELF) addField(k string, v slog.Value) {
+	if b.encoding.Flatten && v.Kind() == slog.KindAny && b.flatten(k, v.Any()) {
+		return
+	}
+	b.add(k, func() { b.slogValue(v) })
+}
 
-	// otherwise convert if necessary
+func (b *BufferGELF) value(v any) {
+	// convert if necessary
 	switch value := v.(type) {
 	case time.Time:
 		b.string(value.Format(time.RFC3339), true)
-	case uint, uint8, uint16, uint32,
-		int, int8, int16, int32, int64:
-		b.buf.WriteString(fmt.Sprint(value))
+	case time.Duration:
+		if b.encoding.DurationUnit > 0 {
+			b.float(float64(value)/float64(b.encoding.DurationUnit), 64)
+			return
+		}
+		b.string(value.String(), true)
+	case int:
+		b.int(int64(value))
+	case int8:
+		b.int(int64(value))
+	case int16:
+		b.int(int64(value))
+	case int32:
+		b.int(int64(value))
+	case int64:
+		b.int(value)
+	case uint:
+		b.uint(uint64(value))
+	case uint8:
+		b.uint(uint64(value))
+	case uint16:
+		b.uint(uint64(value))
+	case uint32:
+		b.uint(uint64(value))
 	case uint64:
 		// NOTE: uint64 is not supported by graylog due to java limitation
 		//       so we're sending them as double for the time being
-		b.buf.WriteString(strconv.FormatFloat(float64(value), 'f', -1, 64))
+		b.buf.Write(strconv.AppendFloat(b.buf.AvailableBuffer(), float64(value), 'f', -1, 64))
 	case float32:
-		b.buf.WriteString(strconv.FormatFloat(float64(value), 'f', -1, 32))
+		b.float(float64(value), 32)
 	case float64:
-		b.buf.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		b.float(value, 64)
 	case bool:
-		b.buf.WriteString(fmt.Sprintf(`"%t"`, value))
+		b.bool(value)
 	case string:
 		b.string(value, true)
+	case []byte:
+		if b.encoding.Base64Bytes {
+			n := base64.StdEncoding.EncodedLen(len(value))
+			b.buf.WriteByte('"')
+			b.buf.Grow(n)
+			p := b.buf.AvailableBuffer()[:n]
+			base64.StdEncoding.Encode(p, value)
+			b.buf.Write(p)
+			b.buf.WriteByte('"')
+			return
+		}
+		b.string(fmt.Sprint(value), true)
+	case json.Marshaler:
+		if !b.encoding.Marshalers || !b.marshaler(value) {
+			b.string(fmt.Sprint(value), true)
+		}
 	default:
 		b.string(fmt.Sprint(value), true)
 	}
 }
 
+func (b *BufferGELF) int(i int64) {
+	b.buf.Write(strconv.AppendInt(b.buf.AvailableBuffer(), i, 10))
+}
+
+func (b *BufferGELF) uint(i uint64) {
+	b.buf.Write(strconv.AppendUint(b.buf.AvailableBuffer(), i, 10))
+}
+
+func (b *BufferGELF) bool(v bool) {
+	if b.encoding.NativeBools {
+		b.buf.Write(strconv.AppendBool(b.buf.AvailableBuffer(), v))
+		return
+	}
+	b.string(strconv.FormatBool(v), true)
+}
+
+// float writes the float, the non-finite ones as strings since JSON doesn't support them.
+func (b *BufferGELF) float(f float64, bitSize int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		b.string(strconv.FormatFloat(f, 'f', -1, bitSize), true)
+		return
+	}
+	b.buf.Write(strconv.AppendFloat(b.buf.AvailableBuffer(), f, 'f', -1, bitSize))
+}
+
+// slogValue writes the common kinds without boxing the value, the same way as value.
+func (b *BufferGELF) slogValue(v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		b.string(v.String(), true)
+	case slog.KindInt64:
+		b.int(v.Int64())
+	case slog.KindFloat64:
+		b.float(v.Float64(), 64)
+	case slog.KindBool:
+		b.bool(v.Bool())
+	default:
+		b.value(v.Any())
+	}
+}
+
 // Complete returns the completed GELF payload with a `\n' when ln is true.
 func (b *BufferGELF) Complete(ln bool) []byte {
+	if b.limits.Payload > 0 {
+		b.fit()
+	}
+	b.marker()
+
 	b.buf.WriteString("}")
 	if ln {
 		b.buf.WriteString("\n")
@@ -118,10 +331,23 @@ func (b *BufferGELF) key(k string) {
 	b.buf.WriteString(":")
 }
 
-// based on https://cs.opensource.google/go/go/+/refs/tags/go1.22.0:src/encoding/json/encode.go;l=956
+// fieldKey writes the key of an additional field, prefixed by `_'.
+func (b *BufferGELF) fieldKey(k string) {
+	b.buf.WriteString(`,"_`)
+	b.content(k, true)
+	b.buf.WriteString(`":`)
+}
+
 func (b *BufferGELF) string(src string, escapeHTML bool) {
+	b.buf.WriteByte('"')
+	b.content(src, escapeHTML)
+	b.buf.WriteByte('"')
+}
+
+// content writes the escaped content of a JSON string.
+// based on https://cs.opensource.google/go/go/+/refs/tags/go1.22.0:src/encoding/json/encode.go;l=956
+func (b *BufferGELF) content(src string, escapeHTML bool) {
 	buf := b.buf
-	buf.WriteByte('"')
 	start := 0
 	for i := 0; i < len(src); {
 		if b := src[i]; b < utf8.RuneSelf {
@@ -204,7 +430,6 @@ func (b *BufferGELF) string(src string, escapeHTML bool) {
 		i += size
 	}
 	b.buf.WriteString(src[start:])
-	b.buf.WriteByte('"')
 }
 
 const hex = "0123456789abcdef"
@@ -69,3 +69,54 @@ func TestBufferGELF(t *testing.T) {
 		t.Errorf("got: %s", b.Bytes())
 	}
 }
+
+func TestBufferGELFDuplicateKeys(t *testing.T) {
+	for _, tc := range []struct {
+		policy   logger.DuplicateKeys
+		expected string
+	}{
+		{policy: logger.DuplicateKeysLastWins, expected: `{"version":"1.1","_a":1,"_k":"c","_b":2}`},
+		{policy: logger.DuplicateKeysFirstWins, expected: `{"version":"1.1","_k":42,"_a":1,"_b":2}`},
+		{policy: logger.DuplicateKeysSuffix, expected: `{"version":"1.1","_k":42,"_a":1,"_k_2":"43","_k_3":"c","_b":2}`},
+		{policy: logger.DuplicateKeysArray, expected: `{"version":"1.1","_k":[42,"43","c"],"_a":1,"_b":2}`},
+	} {
+		b := logger.NewBufferGELF()
+		b.SetDuplicateKeys(tc.policy)
+		b.Add("k", 42)
+		b.Add("a", 1)
+		b.Add("k", "43")
+		b.Add("k", "c")
+		b.Add("b", 2)
+		if got := string(b.Complete(false)); got != tc.expected {
+			t.Errorf("policy %d\n   got: %s\nexpect: %s", tc.policy, got, tc.expected)
+		}
+	}
+}
+
+func TestBufferGELFReset(t *testing.T) {
+	b := logger.NewBufferGELF()
+	b.SetDuplicateKeys(logger.DuplicateKeysFirstWins)
+	b.Add("k", 1)
+
+	b.Reset()
+	b.Add("k", int8(-2))
+	b.Add("k", 3)
+	b.Add("u", uint32(4))
+
+	expected := `{"version":"1.1","_k":-2,"_u":4}`
+	if got := string(b.Complete(false)); got != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+	b.Release()
+
+	// The options are reset by Release.
+	b = logger.NewBufferGELF()
+	defer b.Release()
+	b.Add("k", 1)
+	b.Add("k", 2)
+
+	expected = `{"version":"1.1","_k":1,"_k":2}`
+	if got := string(b.Complete(false)); got != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+}
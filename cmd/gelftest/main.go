@@ -0,0 +1,54 @@
+// Command gelftest receives GELF payloads on the local UDP, TCP and HTTP inputs and prints them
+// with the logger.SlogTextHandler, to see the GELF output in development without running Graylog.
+//
+//	go run github.com/mdouchement/logger/cmd/gelftest -tcp "" -http 127.0.0.1:8080
+//
+// The inputs listen on the loopback interface by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/logger/gelftest"
+)
+
+func main() {
+	var o gelftest.Option
+	flag.StringVar(&o.UDP, "udp", "127.0.0.1:12201", "address of the UDP input, empty to disable it")
+	flag.StringVar(&o.TCP, "tcp", "127.0.0.1:12201", "address of the TCP input, empty to disable it")
+	flag.StringVar(&o.HTTP, "http", "127.0.0.1:12202", "address of the HTTP input, empty to disable it")
+	flag.BoolVar(&o.Validate, "validate", true, "report the messages not complying with the GELF specification")
+	level := flag.String("level", "debug", "minimum level of the printed messages")
+	flag.Parse()
+
+	l, err := logger.ParseSlogLevel(*level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	o.Handler = logger.NewSlogTextHandler(os.Stdout, &logger.SlogTextOption{
+		Level:         l,
+		FullTimestamp: true,
+	})
+	o.OnError = func(err error) {
+		fmt.Fprintln(os.Stderr, "gelftest:", err)
+	}
+
+	s, err := gelftest.NewServer(&o)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	fmt.Fprintf(os.Stderr, "gelftest: listening on udp=%q tcp=%q http=%q\n", s.UDPAddr(), s.TCPAddr(), s.URL())
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	<-c
+}
@@ -2,8 +2,12 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"runtime"
+	"sync"
 	"time"
 
 	"github.com/mgutz/ansi"
@@ -23,30 +27,160 @@ func checkIfTerminal(w io.Writer) bool {
 	}
 }
 
-// This is to not silently overwrite `time`, `msg` and `level` fields when
-// dumping it. If this code wasn't there doing:
-//
-//	logrus.WithField("level", 1).Info("hello")
-//
-// would just silently drop the user provided level. Instead with this code
-// it'll be logged as:
-//
-//	{"level": "info", "fields.level": 1, "msg": "hello", "time": "..."}
-func prefixFieldClashes(data map[string]any) {
-	if t, ok := data["time"]; ok {
-		data["fields.time"] = t
+const defaultTimestampFormat = time.RFC3339
+
+// NopLocker is a sync.Locker that does nothing.
+// It can be used as a handler's Locker when the writer is already safe for concurrent use.
+var NopLocker sync.Locker = nopLocker{}
+
+type nopLocker struct{}
+
+func (nopLocker) Lock()   {}
+func (nopLocker) Unlock() {}
+
+// DeterministicHostname is the hostname used by handlers in deterministic mode
+// when no hostname is explicitly defined.
+const DeterministicHostname = "localhost"
+
+// DeterministicTime is the time used by handlers in deterministic mode
+// when no clock is explicitly defined.
+var DeterministicTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func deterministicClock() time.Time {
+	return DeterministicTime
+}
+
+// A field is a flattened attr, its key being prefixed by its groups.
+type field struct {
+	key   string
+	value slog.Value
+}
+
+// A fieldSink receives the fields produced by an attrWalker.
+type fieldSink interface {
+	addField(key string, value slog.Value)
+}
+
+type fieldSlice []field
+
+func (s *fieldSlice) addField(key string, value slog.Value) {
+	*s = append(*s, field{key: key, value: value})
+}
+
+// An attrWalker resolves, replaces and flattens attrs.
+type attrWalker struct {
+	replace       func(groups []string, a slog.Attr) slog.Attr
+	clashes       *clashResolver
+	deterministic bool
+}
+
+// walk sends each non-group attr to the sink, the groups being flattened in the key with the given prefix.
+// The ReplaceAttr semantics of log/slog are applied: the replace function is called with the groups of
+// the attr for all non-group attrs, a returned attr with an empty key is discarded.
+// The keys clashing with the reserved ones are resolved, the walk stops on the first clash error.
+func (w attrWalker) walk(prefix string, groups []string, attr slog.Attr, sink fieldSink) error {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Value.Kind() == slog.KindGroup {
+		attrs := attr.Value.Group()
+		if len(attrs) == 0 {
+			return nil
+		}
+
+		if attr.Key != "" { // A group with an empty key is inlined.
+			prefix += attr.Key + delimiter
+			groups = append(groups[:len(groups):len(groups)], attr.Key)
+		}
+		for _, a := range attrs {
+			if err := w.walk(prefix, groups, a, sink); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if w.replace != nil {
+		attr = w.replace(groups, attr)
+		attr.Value = attr.Value.Resolve()
+		if attr.Key == "" {
+			return nil
+		}
+
+		if attr.Value.Kind() == slog.KindGroup {
+			return w.walk(prefix, groups, attr, sink)
+		}
 	}
 
-	if m, ok := data["msg"]; ok {
-		data["fields.msg"] = m
+	key := prefix + attr.Key
+	if w.clashes != nil {
+		k, ok, err := w.clashes.resolve(key)
+		if !ok {
+			return err
+		}
+		key = k
 	}
 
-	if l, ok := data["level"]; ok {
-		data["fields.level"] = l
+	sink.addField(key, attrValue(attr, w.deterministic))
+	return nil
+}
+
+// builtin applies the replace function to a built-in attr (e.g. time, level, msg or source).
+func (w attrWalker) builtin(attr slog.Attr) slog.Attr {
+	if w.replace == nil {
+		return attr
 	}
+
+	attr = w.replace(nil, attr)
+	attr.Value = attr.Value.Resolve()
+	return attr
 }
 
-const defaultTimestampFormat = time.RFC3339
+// fields flattens the given attrs, skipping the prefix ones.
+func (w attrWalker) fields(prefix string, groups []string, attrs []slog.Attr) ([]field, error) {
+	fields := make(fieldSlice, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Key == KeyPrefix {
+			continue
+		}
+
+		if err := w.walk(prefix, groups, attr, &fields); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// recordSource returns the source of the record or nil if the record has no program counter.
+func recordSource(r slog.Record) *slog.Source {
+	if r.PC == 0 {
+		return nil
+	}
+
+	fs := runtime.CallersFrames([]uintptr{r.PC})
+	f, _ := fs.Next()
+	return &slog.Source{
+		Function: f.Function,
+		File:     f.File,
+		Line:     f.Line,
+	}
+}
+
+// sourceValue returns the value of a source attr, `file:line' for a *slog.Source.
+func sourceValue(v slog.Value) slog.Value {
+	if src, ok := v.Any().(*slog.Source); ok {
+		return slog.StringValue(fmt.Sprintf("%s:%d", src.File, src.Line))
+	}
+	return v
+}
+
+// attrValue returns the value of the given attr.
+// In deterministic mode, durations are normalized to zero.
+func attrValue(attr slog.Attr, deterministic bool) slog.Value {
+	if deterministic && attr.Value.Kind() == slog.KindDuration {
+		return slog.DurationValue(0)
+	}
+	return attr.Value
+}
 
 var (
 	baseTimestamp      = time.Now()
@@ -120,3 +254,51 @@ func compileColorScheme(s *ColorScheme) *compiledColorScheme {
 		TimestampColor:  getCompiledColor(s.TimestampStyle, defaultColorScheme.TimestampStyle),
 	}
 }
+
+// colorCodes holds the ansi codes of a color scheme, used to write colors without allocating.
+type colorCodes struct {
+	InfoLevel  string
+	WarnLevel  string
+	ErrorLevel string
+	FatalLevel string
+	PanicLevel string
+	DebugLevel string
+	Prefix     string
+	Timestamp  string
+}
+
+var (
+	noColorCodes      = &colorCodes{}
+	defaultColorCodes = compileColorCodes(defaultColorScheme)
+)
+
+func getColorCode(main string, fallback string) string {
+	if main == "" {
+		main = fallback
+	}
+	return ansi.ColorCode(main)
+}
+
+func compileColorCodes(s *ColorScheme) *colorCodes {
+	return &colorCodes{
+		InfoLevel:  getColorCode(s.InfoLevelStyle, defaultColorScheme.InfoLevelStyle),
+		WarnLevel:  getColorCode(s.WarnLevelStyle, defaultColorScheme.WarnLevelStyle),
+		ErrorLevel: getColorCode(s.ErrorLevelStyle, defaultColorScheme.ErrorLevelStyle),
+		FatalLevel: getColorCode(s.FatalLevelStyle, defaultColorScheme.FatalLevelStyle),
+		PanicLevel: getColorCode(s.PanicLevelStyle, defaultColorScheme.PanicLevelStyle),
+		DebugLevel: getColorCode(s.DebugLevelStyle, defaultColorScheme.DebugLevelStyle),
+		Prefix:     getColorCode(s.PrefixStyle, defaultColorScheme.PrefixStyle),
+		Timestamp:  getColorCode(s.TimestampStyle, defaultColorScheme.TimestampStyle),
+	}
+}
+
+// appendColored appends s wrapped by the color code, like the functions returned by ansi.ColorFunc.
+func appendColored(b []byte, code string, s string) []byte {
+	if code == "" || s == "" {
+		return append(b, s...)
+	}
+
+	b = append(b, code...)
+	b = append(b, s...)
+	return append(b, ansi.Reset...)
+}
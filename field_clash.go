@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// FieldClashes is the strategy applied to the fields whose keys clash with the reserved keys
+// written by a formatter/handler (e.g. `time', `msg', `level' and `source' for the text ones,
+// `id', `host', `level_name', `file' and `line' for the GELF ones).
+type FieldClashes int
+
+const (
+	// FieldClashesPrefix renames the clashing fields with the ClashPrefix, e.g. `fields.level'.
+	FieldClashesPrefix FieldClashes = iota
+	// FieldClashesDrop drops the clashing fields.
+	FieldClashesDrop
+	// FieldClashesError discards the record with an error wrapping ErrFieldClash.
+	FieldClashesError
+)
+
+// DefaultClashPrefix is the default prefix of the fields renamed by FieldClashesPrefix.
+const DefaultClashPrefix = "fields."
+
+// ErrFieldClash is the error returned when a field clashes with a reserved key and FieldClashesError is used.
+var ErrFieldClash = errors.New("field clashes with a reserved key")
+
+// A clashResolver applies a FieldClashes strategy to the reserved keys.
+type clashResolver struct {
+	strategy FieldClashes
+	prefix   string
+	reserved []string
+	// isReserved reports the other reserved keys, e.g. the ones reserved by Graylog.
+	isReserved func(key string) bool
+}
+
+func newClashResolver(strategy FieldClashes, prefix string, reserved ...string) *clashResolver {
+	if prefix == "" {
+		prefix = DefaultClashPrefix
+	}
+
+	return &clashResolver{
+		strategy: strategy,
+		prefix:   prefix,
+		reserved: reserved,
+	}
+}
+
+// resolve returns the key of the field, ok being false when the field is dropped.
+func (r *clashResolver) resolve(key string) (k string, ok bool, err error) {
+	if !r.reserves(key) {
+		return key, true, nil
+	}
+
+	switch r.strategy {
+	case FieldClashesDrop:
+		return "", false, nil
+	case FieldClashesError:
+		return "", false, fmt.Errorf("%w: %s", ErrFieldClash, key)
+	default:
+		return r.prefix + key, true, nil
+	}
+}
+
+// resolveData returns the data with the clashing fields resolved.
+// A renamed field is prefixed again while its key is already used so no field is overwritten.
+// The given data is returned as is when there is no clash, otherwise a copy is returned.
+func (r *clashResolver) resolveData(data map[string]any) (map[string]any, error) {
+	var clashing []string
+	for key := range data {
+		if r.reserves(key) {
+			clashing = append(clashing, key)
+		}
+	}
+	if clashing == nil {
+		return data, nil
+	}
+	slices.Sort(clashing) // The renamed fields don't depend on the map order.
+
+	resolved := maps.Clone(data)
+	for _, key := range clashing {
+		value := data[key]
+		k, ok, err := r.resolve(key)
+		if err != nil {
+			return nil, err
+		}
+		delete(resolved, key)
+		if !ok {
+			continue
+		}
+		for {
+			if _, exists := resolved[k]; !exists {
+				break
+			}
+			k = r.prefix + k
+		}
+		resolved[k] = value
+	}
+
+	return resolved, nil
+}
+
+func (r *clashResolver) reserves(key string) bool {
+	return slices.Contains(r.reserved, key) || r.isReserved != nil && r.isReserved(key)
+}
@@ -0,0 +1,115 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSlogTextFieldClashes(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		option   logger.SlogTextOption
+		expected string
+	}{
+		{
+			name:     "prefix",
+			option:   logger.SlogTextOption{DisableTimestamp: true},
+			expected: "level=INFO msg=message fields.level=user fields.msg=user fields.time=user g.level=kept source=user\n",
+		},
+		{
+			name:     "custom prefix",
+			option:   logger.SlogTextOption{DisableTimestamp: true, AddSource: true, ClashPrefix: "user_", ReplaceAttr: dropSource},
+			expected: "level=INFO msg=message g.level=kept user_level=user user_msg=user user_source=user user_time=user\n",
+		},
+		{
+			name:     "drop",
+			option:   logger.SlogTextOption{DisableTimestamp: true, FieldClashes: logger.FieldClashesDrop},
+			expected: "level=INFO msg=message g.level=kept source=user\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			l := slog.New(logger.NewSlogTextHandler(w, &tc.option)).With("time", "user")
+
+			l.Info("message", "level", "user", "msg", "user", "source", "user", slog.Group("g", "level", "kept"))
+			if w.String() != tc.expected {
+				t.Errorf("\n   got: %q\nexpect: %q", w, tc.expected)
+			}
+		})
+	}
+}
+
+func dropSource(groups []string, a slog.Attr) slog.Attr {
+	if groups == nil && a.Key == slog.SourceKey {
+		if _, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.Attr{}
+		}
+	}
+	return a
+}
+
+func TestSlogFieldClashesError(t *testing.T) {
+	for _, h := range []func(w *bytes.Buffer) slog.Handler{
+		func(w *bytes.Buffer) slog.Handler {
+			return logger.NewSlogTextHandler(w, &logger.SlogTextOption{FieldClashes: logger.FieldClashesError})
+		},
+		func(w *bytes.Buffer) slog.Handler {
+			return logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{FieldClashes: logger.FieldClashesError})
+		},
+	} {
+		w := new(bytes.Buffer)
+		l := slog.New(h(w))
+
+		if err := l.Handler().Handle(context.Background(), slog.NewRecord(logger.DeterministicTime, slog.LevelInfo, "message", 0)); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+		w.Reset()
+
+		record := slog.NewRecord(logger.DeterministicTime, slog.LevelInfo, "message", 0)
+		record.AddAttrs(slog.String("level", "user"), slog.String("level_name", "user"))
+		if err := l.Handler().Handle(context.Background(), record); !errors.Is(err, logger.ErrFieldClash) {
+			t.Errorf("got %v, expect ErrFieldClash", err)
+		}
+
+		hh := l.With("id", 42, "time", "user").Handler()
+		if err := hh.Handle(context.Background(), slog.NewRecord(logger.DeterministicTime, slog.LevelInfo, "message", 0)); !errors.Is(err, logger.ErrFieldClash) {
+			t.Errorf("got %v, expect ErrFieldClash", err)
+		}
+
+		if w.Len() != 0 {
+			t.Errorf("the records must be discarded, got: %s", w)
+		}
+	}
+}
+
+func TestSlogGELFFieldClashes(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true})).With("id", 42)
+
+	l.Info("message", "host", "user", "level_name", "user", "file", "kept")
+
+	expected := `{"version":"1.1","_fields.id":42,"_fields.host":"user","_fields.level_name":"user","_file":"kept","host":"localhost","timestamp":946684800,"level":6,"short_message":"message","_level_name":"INFO"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestLogrusTextFieldClashes(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusTextFormatter{DisableTimestamp: true, FieldClashes: logger.FieldClashesDrop})
+
+	ll.WithFields(logrus.Fields{"level": "user", "msg": "user", "index": 42, "key": "value"}).Info("message")
+
+	expected := "index=1 level=info msg=message key=value\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+	}
+}
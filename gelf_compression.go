@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A GELFCompression is the compression of the payloads sent by the GELF writers.
+type GELFCompression int
+
+const (
+	// GELFCompressionNone sends the payloads as is.
+	GELFCompressionNone GELFCompression = iota
+	// GELFCompressionGzip compresses the payloads with gzip.
+	GELFCompressionGzip
+	// GELFCompressionZlib compresses the payloads with zlib.
+	GELFCompressionZlib
+)
+
+const maxPooledCompressedBuffer = 64 << 10
+
+type (
+	// gelfCompressor compresses the payloads with pooled compressors.
+	gelfCompressor struct {
+		compression GELFCompression
+		threshold   int
+		pool        sync.Pool
+	}
+
+	compressWriter interface {
+		io.WriteCloser
+		Reset(w io.Writer)
+	}
+
+	compressState struct {
+		buf bytes.Buffer
+		w   compressWriter
+	}
+)
+
+// newGELFCompressor returns a gelfCompressor compressing the payloads not shorter than the threshold.
+// A zero level uses the default compression level.
+func newGELFCompressor(compression GELFCompression, level, threshold int) (*gelfCompressor, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var create func() (compressWriter, error)
+	switch compression {
+	case GELFCompressionNone:
+	case GELFCompressionGzip:
+		create = func() (compressWriter, error) { return gzip.NewWriterLevel(io.Discard, level) }
+	case GELFCompressionZlib:
+		create = func() (compressWriter, error) { return zlib.NewWriterLevel(io.Discard, level) }
+	default:
+		return nil, fmt.Errorf("gelf: unknown compression %d", compression)
+	}
+
+	c := &gelfCompressor{
+		compression: compression,
+		threshold:   threshold,
+	}
+	if create == nil {
+		return c, nil
+	}
+
+	if _, err := create(); err != nil { // Checks the level.
+		return nil, fmt.Errorf("gelf: %w", err)
+	}
+	c.pool.New = func() any {
+		w, _ := create()
+		return &compressState{w: w}
+	}
+	return c, nil
+}
+
+// compresses returns true if the payloads of size n are compressed.
+func (c *gelfCompressor) compresses(n int) bool {
+	return c.compression != GELFCompressionNone && n >= c.threshold
+}
+
+// compress calls fn with the payload p, compressed when needed.
+// The compressed payload must not be retained after fn returns.
+func (c *gelfCompressor) compress(p []byte, fn func([]byte) error) error {
+	if !c.compresses(len(p)) {
+		return fn(p)
+	}
+
+	s := c.pool.Get().(*compressState)
+	defer c.release(s)
+
+	s.buf.Reset()
+	s.w.Reset(&s.buf)
+	if _, err := s.w.Write(p); err != nil {
+		return err
+	}
+	if err := s.w.Close(); err != nil {
+		return err
+	}
+	return fn(s.buf.Bytes())
+}
+
+func (c *gelfCompressor) release(s *compressState) {
+	if s.buf.Cap() > maxPooledCompressedBuffer {
+		return
+	}
+	c.pool.Put(s)
+}
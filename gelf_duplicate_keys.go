@@ -0,0 +1,188 @@
+package logger
+
+import "strconv"
+
+// DuplicateKeys is the policy applied to the additional fields of a GELF payload added several times
+// (e.g. by a child handler and a record), JSON objects with duplicate keys being ambiguous.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeysLastWins keeps the last value of the key.
+	DuplicateKeysLastWins DuplicateKeys = iota
+	// DuplicateKeysFirstWins keeps the first value of the key.
+	DuplicateKeysFirstWins
+	// DuplicateKeysSuffix keeps all the values, the nth occurrence of `key' being renamed `key_n'.
+	DuplicateKeysSuffix
+	// DuplicateKeysArray merges all the values of the key in an array.
+	DuplicateKeysArray
+)
+
+// A gelfField is the position of an additional field in the buffer.
+type gelfField struct {
+	key   string
+	start int // Offset of the field, including the leading comma.
+	value int // Offset of the value.
+	end   int
+	array bool // The value is an array of the duplicated values.
+}
+
+// SetDuplicateKeys sets the policy applied to the additional fields added several times.
+// By default, a BufferGELF writes all the fields as is, even if their keys are duplicated.
+func (b *BufferGELF) SetDuplicateKeys(policy DuplicateKeys) {
+	b.dedupe = true
+	b.duplicates = policy
+}
+
+// add adds an additional field whose value is written by the given function.
+func (b *BufferGELF) add(k string, write func()) {
+	b.addValue(k, write, b.limits.FieldValue)
+}
+
+// builtin adds an additional field written by the handler/formatter itself (e.g. `_level_name'),
+// which is not truncated by the FieldValue limit.
+func (b *BufferGELF) builtin(k string, v any) {
+	b.addValue(k, func() { b.value(v) }, 0)
+}
+
+// addValue adds an additional field whose string value is truncated to max bytes when max is positive.
+func (b *BufferGELF) addValue(k string, write func(), max int) {
+	k, ok := b.fieldName(k)
+	if !ok || isGELFReserved(k) {
+		return
+	}
+
+	if b.dedupe {
+		if i := b.field(k); i >= 0 {
+			switch b.duplicates {
+			case DuplicateKeysFirstWins:
+				return
+			case DuplicateKeysSuffix:
+				k = b.suffixed(k)
+			case DuplicateKeysArray:
+				b.appendToArray(i, write)
+				return
+			default:
+				b.remove(i)
+			}
+		}
+	}
+
+	f := gelfField{key: k, start: b.buf.Len()}
+	b.fieldKey(k)
+	f.value = b.buf.Len()
+	write()
+	f.end = b.buf.Len()
+
+	if max > 0 {
+		b.cut(&f, max, "_")
+	}
+	if b.tracking() {
+		b.fields = append(b.fields, f)
+	}
+}
+
+// tracking returns true if the positions of the additional fields are tracked.
+func (b *BufferGELF) tracking() bool {
+	return b.dedupe || b.limits.Payload > 0
+}
+
+// fragment writes the fields already rendered by a BufferGELF without header.
+func (b *BufferGELF) fragment(p []byte, fields []gelfField, truncated []string) {
+	offset := b.buf.Len()
+	b.buf.Write(p)
+	for _, name := range truncated {
+		b.markTruncated(name)
+	}
+
+	if !b.tracking() {
+		return
+	}
+	for _, f := range fields {
+		f.start += offset
+		f.value += offset
+		f.end += offset
+		b.fields = append(b.fields, f)
+	}
+}
+
+// field returns the index of the field with the given key or -1.
+func (b *BufferGELF) field(k string) int {
+	for i := range b.fields {
+		if b.fields[i].key == k {
+			return i
+		}
+	}
+	return -1
+}
+
+// suffixed returns the first free `key_n' key.
+func (b *BufferGELF) suffixed(k string) string {
+	for n := 2; ; n++ {
+		key := k + "_" + strconv.Itoa(n)
+		if b.field(key) < 0 {
+			return key
+		}
+	}
+}
+
+func (b *BufferGELF) remove(i int) {
+	f := b.fields[i]
+	b.splice(f.start, f.end, nil)
+	b.fields = append(b.fields[:i], b.fields[i+1:]...)
+}
+
+// appendToArray appends the value written by the given function to the values of the field i.
+func (b *BufferGELF) appendToArray(i int, write func()) {
+	start := b.buf.Len()
+	write()
+
+	bs := b.buf.Bytes()
+	f := b.fields[i]
+	b.scratch = b.scratch[:0]
+	if f.array {
+		b.scratch = append(b.scratch, bs[f.value:f.end-1]...) // Without the closing bracket.
+	} else {
+		b.scratch = append(b.scratch, '[')
+		b.scratch = append(b.scratch, bs[f.value:f.end]...)
+	}
+	b.scratch = append(b.scratch, ',')
+	b.scratch = append(b.scratch, bs[start:]...)
+	b.scratch = append(b.scratch, ']')
+	b.buf.Truncate(start)
+
+	b.splice(f.value, f.end, b.scratch)
+	b.fields[i].end = f.value + len(b.scratch)
+	b.fields[i].array = true
+}
+
+// splice replaces the bytes from start to end by p, p must not be a part of the buffer.
+// The positions of the following fields are shifted accordingly.
+func (b *BufferGELF) splice(start, end int, p []byte) {
+	n := b.buf.Len()
+	delta := len(p) - (end - start)
+	if delta > 0 {
+		b.buf.Write(p[:delta]) // Grows the buffer, the bytes are overwritten below.
+	}
+
+	bs := b.buf.Bytes()
+	copy(bs[start+len(p):], bs[end:n])
+	copy(bs[start:], p)
+	if delta < 0 {
+		b.buf.Truncate(n + delta)
+	}
+
+	for i := range b.fields {
+		b.fields[i].shift(end, delta)
+	}
+	b.short.shift(end, delta)
+	b.full.shift(end, delta)
+}
+
+// shift shifts the field by delta when it starts after the given offset.
+func (f *gelfField) shift(offset, delta int) {
+	if f.end > 0 && f.start >= offset {
+		f.start += delta
+		f.value += delta
+		f.end += delta
+	}
+}
@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// A GELFEncoding holds the encoding options of the GELF additional field values.
+// The zero value writes the booleans as strings and the values that are not strings, numbers or times with fmt.Sprint.
+// The non-finite floats (NaN and ±Inf) are always written as strings so the payload is valid JSON.
+type GELFEncoding struct {
+	// NativeBools writes the booleans as JSON booleans instead of "true"/"false" strings.
+	// It breaks the GELF 1.1 compliance, whose additional fields are only strings and numbers,
+	// so the payloads are rejected by GELFMessage.Validate (e.g. by the gelftest.NewTestServer).
+	NativeBools bool
+
+	// DurationUnit writes the durations as numbers of this unit (e.g. time.Millisecond or time.Second)
+	// instead of strings like "1.5s".
+	DurationUnit time.Duration
+
+	// Flatten writes the maps, structs, slices and arrays as dotted fields encoded with encoding/json
+	// (e.g. `_user.name' and `_tags.0'), GELF values being only strings and numbers.
+	// The nested empty objects/arrays and nulls are written as their JSON strings (e.g. `"[]"'),
+	// and a value without any field (e.g. an empty map) is written as if it were not flattened.
+	Flatten bool
+
+	// Marshalers encodes the json.Marshaler values with their MarshalJSON method,
+	// the objects and arrays being written as JSON strings unless Flatten is set.
+	Marshalers bool
+
+	// Base64Bytes writes the byte slices as base64 strings.
+	Base64Bytes bool
+}
+
+// SetEncoding sets the encoding options of the additional field values.
+func (b *BufferGELF) SetEncoding(e GELFEncoding) {
+	b.encoding = e
+}
+
+// flatten adds the nested value v as dotted fields, returning false when v is not nested.
+func (b *BufferGELF) flatten(k string, v any) bool {
+	if !b.nested(v) {
+		return false
+	}
+
+	p, err := json.Marshal(v)
+	if err != nil || len(p) == 0 || (p[0] != '{' && p[0] != '[') {
+		return false
+	}
+
+	var tree any
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&tree); err != nil || empty(tree) {
+		return false
+	}
+
+	b.flattenTree(k, tree)
+	return true
+}
+
+func (b *BufferGELF) flattenTree(k string, v any) {
+	if empty(v) {
+		b.add(k, func() { b.string(emptyJSON(v), true) })
+		return
+	}
+
+	switch value := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		for _, key := range keys {
+			b.flattenTree(k+delimiter+key, value[key])
+		}
+	case []any:
+		for i, e := range value {
+			b.flattenTree(k+delimiter+strconv.Itoa(i), e)
+		}
+	case json.Number:
+		b.add(k, func() { b.buf.WriteString(string(value)) })
+	default:
+		b.Add(k, value)
+	}
+}
+
+// empty returns true if the decoded JSON value v has no field to write: null, an empty object or an empty array.
+func empty(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		return len(value) == 0
+	case []any:
+		return len(value) == 0
+	}
+	return false
+}
+
+// emptyJSON returns the JSON text of the empty value v.
+func emptyJSON(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "{}"
+	case []any:
+		return "[]"
+	}
+	return "null"
+}
+
+// nested returns true if v is written as dotted fields.
+func (b *BufferGELF) nested(v any) bool {
+	switch v.(type) {
+	case nil, time.Time, []byte, error, fmt.Stringer:
+		return false
+	case json.Marshaler:
+		return b.encoding.Marshalers
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// marshaler writes the JSON encoding of v, the objects and arrays as JSON strings.
+func (b *BufferGELF) marshaler(v json.Marshaler) bool {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+
+	if p[0] == '{' || p[0] == '[' {
+		b.string(string(p), true)
+		return true
+	}
+	b.buf.Write(p)
+	return true
+}
@@ -0,0 +1,147 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+type user struct {
+	Name  string   `json:"name"`
+	Admin bool     `json:"admin"`
+	Tags  []string `json:"tags"`
+	Hash  string   `json:"-"`
+}
+
+type point struct{ X, Y int }
+
+func (p point) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{p.X, p.Y})
+}
+
+type celsius float64
+
+func (c celsius) MarshalJSON() ([]byte, error) {
+	return json.Marshal(float64(c))
+}
+
+func TestBufferGELFEncoding(t *testing.T) {
+	values := []struct {
+		k string
+		v any
+	}{
+		{"bool", true},
+		{"duration", 1500 * time.Millisecond},
+		{"bytes", []byte("hello")},
+		{"nan", math.NaN()},
+		{"inf", math.Inf(-1)},
+		{"f32", float32(math.Inf(1))},
+		{"user", &user{Name: "john", Admin: true, Tags: []string{"a", "b"}, Hash: "secret"}},
+		{"map", map[string]any{"b": 2, "a": map[string]int{"x": 1}}},
+		{"point", point{1, 2}},
+		{"temp", celsius(21.5)},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		encoding logger.GELFEncoding
+		expected string
+	}{
+		{
+			name: "default",
+			expected: `{"version":"1.1","_bool":"true","_duration":"1.5s","_bytes":"[104 101 108 108 111]","_nan":"NaN","_inf":"-Inf","_f32":"+Inf",` +
+				`"_user":"\u0026{john true [a b] secret}","_map":"map[a:map[x:1] b:2]","_point":"{1 2}","_temp":"21.5"}`,
+		},
+		{
+			name: "native",
+			encoding: logger.GELFEncoding{
+				NativeBools:  true,
+				DurationUnit: time.Millisecond,
+				Base64Bytes:  true,
+				Marshalers:   true,
+			},
+			expected: `{"version":"1.1","_bool":true,"_duration":1500,"_bytes":"aGVsbG8=","_nan":"NaN","_inf":"-Inf","_f32":"+Inf",` +
+				`"_user":"\u0026{john true [a b] secret}","_map":"map[a:map[x:1] b:2]","_point":"[1,2]","_temp":21.5}`,
+		},
+		{
+			name: "flatten",
+			encoding: logger.GELFEncoding{
+				NativeBools:  true,
+				DurationUnit: time.Second,
+				Flatten:      true,
+				Marshalers:   true,
+			},
+			expected: `{"version":"1.1","_bool":true,"_duration":1.5,"_bytes":"[104 101 108 108 111]","_nan":"NaN","_inf":"-Inf","_f32":"+Inf",` +
+				`"_user.admin":true,"_user.name":"john","_user.tags.0":"a","_user.tags.1":"b","_map.a.x":1,"_map.b":2,"_point.0":1,"_point.1":2,"_temp":21.5}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gelf := logger.NewBufferGELF()
+			gelf.SetEncoding(tc.encoding)
+			for _, v := range values {
+				gelf.Add(v.k, v.v)
+			}
+
+			payload := gelf.Complete(false)
+			if string(payload) != tc.expected {
+				t.Errorf("\n   got: %s\nexpect: %s", payload, tc.expected)
+			}
+			if !json.Valid(payload) {
+				t.Errorf("invalid JSON: %s", payload)
+			}
+		})
+	}
+}
+
+func TestBufferGELFFlattenEmpty(t *testing.T) {
+	gelf := logger.NewBufferGELF()
+	defer gelf.Release()
+	gelf.SetEncoding(logger.GELFEncoding{Flatten: true})
+
+	gelf.Add("empty", map[string]int{})
+	gelf.Add("tags", []string{})
+	gelf.Add("opaque", struct{ x int }{1})
+	gelf.Add("nested", map[string]any{"a": nil, "b": []int{}, "c": map[string]int{}, "d": 1})
+
+	expected := `{"version":"1.1","_empty":"map[]","_tags":"[]","_opaque":"{1}","_nested.a":"null","_nested.b":"[]","_nested.c":"{}","_nested.d":1}`
+	if got := string(gelf.Complete(false)); got != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+}
+
+func TestSlogGELFEncoding(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+		Hostname: "localhost",
+		Clock:    func() time.Time { return logger.DeterministicTime },
+		Encoding: logger.GELFEncoding{NativeBools: true, DurationUnit: time.Millisecond, Flatten: true},
+	})).With("enabled", false)
+
+	l.Info("message", "elapsed", 2*time.Second, "nan", math.NaN(), "user", user{Name: "john"})
+
+	expected := `{"version":"1.1","_enabled":false,"_elapsed":2000,"_nan":"NaN","_user.admin":false,"_user.name":"john","_user.tags":"null","host":"localhost","timestamp":946684800,"level":6,"short_message":"message","_level_name":"INFO"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestLogrusGELFEncoding(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusGELFFormatter{Hostname: "hostname", Encoding: logger.GELFEncoding{NativeBools: true, Flatten: true}})
+
+	ll.WithField("user", map[string]any{"name": "john", "admin": true}).Info("message")
+
+	for _, field := range []string{`"_user.name":"john"`, `"_user.admin":true`} {
+		if !bytes.Contains(w.Bytes(), []byte(field)) {
+			t.Errorf("%s not found in %s", field, w)
+		}
+	}
+}
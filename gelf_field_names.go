@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// InvalidFieldNames is the policy applied to the GELF additional fields whose names don't match `^[\w\.\-]*$',
+// Graylog silently dropping them.
+type InvalidFieldNames int
+
+const (
+	// InvalidFieldNamesReplace replaces the invalid characters of the names by `_'.
+	InvalidFieldNamesReplace InvalidFieldNames = iota
+	// InvalidFieldNamesDrop drops the fields with an invalid name.
+	InvalidFieldNamesDrop
+	// InvalidFieldNamesReport drops the fields with an invalid name and reports them
+	// with an error wrapping ErrInvalidFieldName.
+	InvalidFieldNamesReport
+)
+
+// ErrInvalidFieldName is the error reported for the GELF additional fields with an invalid name.
+var ErrInvalidFieldName = errors.New("gelf: invalid field name")
+
+// gelfReservedNames are the names of the additional fields reserved by Graylog, with or without their leading `_',
+// the names starting with `gl2_' being reserved too.
+var gelfReservedNames = []string{"id", "source", "message", "full_message", "timestamp", "streams"}
+
+// isGELFReserved returns true if the additional field name is reserved by Graylog.
+func isGELFReserved(k string) bool {
+	k = strings.TrimPrefix(k, "_")
+	return slices.Contains(gelfReservedNames, k) || strings.HasPrefix(k, "gl2_")
+}
+
+// SetInvalidFieldNames sets the policy applied to the additional fields with an invalid name,
+// report being called with the errors of InvalidFieldNamesReport.
+// By default, the invalid characters are replaced.
+func (b *BufferGELF) SetInvalidFieldNames(policy InvalidFieldNames, report func(err error)) {
+	b.invalidNames = policy
+	b.report = report
+}
+
+// fieldName returns the name of the additional field, ok being false when the field is dropped.
+func (b *BufferGELF) fieldName(k string) (name string, ok bool) {
+	i := invalidFieldNameIndex(k)
+	if i < 0 {
+		return k, true
+	}
+
+	switch b.invalidNames {
+	case InvalidFieldNamesDrop:
+		return "", false
+	case InvalidFieldNamesReport:
+		if b.report != nil {
+			b.report(fmt.Errorf("%w: %q", ErrInvalidFieldName, k))
+		}
+		return "", false
+	}
+
+	s := []byte(k[:i])
+	for _, r := range k[i:] {
+		if r < utf8.RuneSelf && validFieldNameChar(byte(r)) {
+			s = append(s, byte(r))
+		} else {
+			s = append(s, '_')
+		}
+	}
+	return string(s), true
+}
+
+// invalidFieldNameIndex returns the index of the first invalid character of the name or -1.
+func invalidFieldNameIndex(k string) int {
+	for i := 0; i < len(k); i++ {
+		if !validFieldNameChar(k[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func validFieldNameChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' || c == '.' || c == '-'
+}
@@ -0,0 +1,94 @@
+package logger_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestBufferGELFInvalidFieldNames(t *testing.T) {
+	var reported []error
+	for _, tc := range []struct {
+		name     string
+		policy   logger.InvalidFieldNames
+		expected string
+	}{
+		{
+			name:     "replace",
+			policy:   logger.InvalidFieldNamesReplace,
+			expected: `{"version":"1.1","_valid.key-1":1,"_with_space":2,"_a_b":3,"_caf_":4,"_fields.id":6}`,
+		},
+		{
+			name:     "drop",
+			policy:   logger.InvalidFieldNamesDrop,
+			expected: `{"version":"1.1","_valid.key-1":1,"_fields.id":6}`,
+		},
+		{
+			name:     "report",
+			policy:   logger.InvalidFieldNamesReport,
+			expected: `{"version":"1.1","_valid.key-1":1,"_fields.id":6}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gelf := logger.NewBufferGELF()
+			gelf.SetInvalidFieldNames(tc.policy, func(err error) { reported = append(reported, err) })
+
+			gelf.Add("valid.key-1", 1)
+			gelf.Add("with space", 2)
+			gelf.Add("a/b", 3)
+			gelf.Add("café", 4)
+			for _, k := range []string{"id", "_id", "source", "message", "full_message", "timestamp", "streams", "gl2_remote_ip", "gl2 x"} {
+				gelf.Add(k, 5)
+			}
+			gelf.Add("fields.id", 6)
+
+			if got := string(gelf.Bytes()); got+"}" != tc.expected {
+				t.Errorf("\n   got: %s\nexpect: %s", got+"}", tc.expected)
+			}
+		})
+	}
+
+	expected := `[gelf: invalid field name: "with space" gelf: invalid field name: "a/b" gelf: invalid field name: "café" gelf: invalid field name: "gl2 x"]`
+	if fmt.Sprint(reported) != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", reported, expected)
+	}
+	for _, err := range reported {
+		if !errors.Is(err, logger.ErrInvalidFieldName) {
+			t.Errorf("got %v, expect ErrInvalidFieldName", err)
+		}
+	}
+}
+
+func TestSlogGELFFieldNames(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+
+	l.Info("message", "source", "api", "gl2_source_node", "node", slog.Group("my group", "source", "kept", slog.Group("ü", "k/v", 1)))
+
+	expected := `{"version":"1.1","_fields.source":"api","_fields.gl2_source_node":"node","_my_group.source":"kept","_my_group._.k_v":1,"host":"localhost","timestamp":946684800,"level":6,"short_message":"message","_level_name":"INFO"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestLogrusGELFFieldNames(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusGELFFormatter{Hostname: "hostname", InvalidFieldNames: logger.InvalidFieldNamesDrop})
+
+	ll.WithFields(logrus.Fields{"source": "api", "bad key": 1}).Info("message")
+
+	gelf := w.String()
+	if !bytes.Contains(w.Bytes(), []byte(`"_fields.source":"api"`)) {
+		t.Errorf("the reserved field must be renamed: %s", gelf)
+	}
+	if bytes.Contains(w.Bytes(), []byte(`bad`)) {
+		t.Errorf("the invalid field must be dropped: %s", gelf)
+	}
+}
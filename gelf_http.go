@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGELFHTTPTimeout       = 10 * time.Second
+	defaultGELFHTTPFlushInterval = time.Second
+	defaultGELFHTTPMaxRetries    = 3
+)
+
+type (
+	// A GELFHTTPOption holds GELFHTTPWriter's options.
+	GELFHTTPOption struct {
+		// Client is the client sending the requests (a client with a 10s timeout by default).
+		Client *http.Client
+		// Header holds the headers added to the requests (e.g. `Authorization').
+		Header http.Header
+		// Compression is the compression of the request bodies, sent as is by default.
+		Compression GELFCompression
+		// CompressionLevel is the gzip/zlib compression level, the default level when zero.
+		CompressionLevel int
+		// CompressionThreshold is the size under which the request bodies are sent uncompressed.
+		CompressionThreshold int
+		// BatchSize is the maximum number of payloads sent in one request, separated by a `\n'.
+		// The payloads are sent one by one by default.
+		// A BatchSize greater than 1 requires the `Enable Bulk Receiving' option of the Graylog GELF HTTP input,
+		// otherwise only the first payload of each request is received.
+		BatchSize int
+		// FlushInterval is the delay after which an incomplete batch is sent (1s by default).
+		FlushInterval time.Duration
+		// MaxRetries is the number of retries of the requests failing with a 5xx status or a network error (3 by default).
+		// A negative value disables the retries. The retries are interrupted by Close.
+		MaxRetries int
+		// MinBackoff is the delay before the first retry (100ms by default),
+		// doubled after each failed retry up to MaxBackoff (30s by default).
+		MinBackoff time.Duration
+		MaxBackoff time.Duration
+	}
+
+	// A GELFHTTPWriter posts the written GELF payloads to a Graylog GELF HTTP input.
+	// When batching, the incomplete batches are sent in background and their errors discarded,
+	// use Flush to send them synchronously.
+	// The requests are sent without holding the writer's lock, so a slow input doesn't block the other writes.
+	// It is safe for concurrent use.
+	GELFHTTPWriter struct {
+		opt        GELFHTTPOption
+		url        string
+		encoding   string
+		compressor *gelfCompressor
+		mu         sync.Mutex
+		batch      bytes.Buffer
+		count      int
+		closed     bool
+		done       chan struct{}
+	}
+)
+
+// NewGELFHTTPWriter returns a new GELFHTTPWriter posting the payloads to the given URL.
+// The path `/gelf' is used when the URL has none (e.g. `http://graylog:12201').
+func NewGELFHTTPWriter(rawURL string, o *GELFHTTPOption) (*GELFHTTPWriter, error) {
+	if o == nil {
+		o = &GELFHTTPOption{}
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: defaultGELFHTTPTimeout}
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 1
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = defaultGELFHTTPFlushInterval
+	}
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultGELFHTTPMaxRetries
+	}
+	if o.MinBackoff == 0 {
+		o.MinBackoff = defaultGELFTCPMinBackoff
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = defaultGELFTCPMaxBackoff
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" {
+		u.Path = "/gelf"
+	}
+
+	compressor, err := newGELFCompressor(o.Compression, o.CompressionLevel, o.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &GELFHTTPWriter{
+		opt:        *o,
+		url:        u.String(),
+		compressor: compressor,
+		done:       make(chan struct{}),
+	}
+	switch o.Compression {
+	case GELFCompressionGzip:
+		w.encoding = "gzip"
+	case GELFCompressionZlib:
+		w.encoding = "deflate"
+	}
+
+	if w.opt.BatchSize > 1 {
+		go w.flushLoop()
+	}
+	return w, nil
+}
+
+// Write adds the payload p, a GELF message usually terminated by a `\n', to the batch
+// and sends the batch when it is complete.
+// Each call to Write must contain exactly one GELF message.
+func (w *GELFHTTPWriter) Write(p []byte) (int, error) {
+	payload := p
+	if n := len(payload); n > 0 && payload[n-1] == '\n' {
+		payload = payload[:n-1]
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, net.ErrClosed
+	}
+
+	if w.count > 0 {
+		w.batch.WriteByte('\n')
+	}
+	w.batch.Write(payload)
+	w.count++
+
+	var batch []byte
+	if w.count >= w.opt.BatchSize {
+		batch = w.take()
+	}
+	w.mu.Unlock()
+
+	if err := w.send(batch); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends the incomplete batch.
+func (w *GELFHTTPWriter) Flush() error {
+	w.mu.Lock()
+	batch := w.take()
+	w.mu.Unlock()
+
+	return w.send(batch)
+}
+
+// Close sends the incomplete batch, without retrying it, and stops the writer.
+func (w *GELFHTTPWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return net.ErrClosed
+	}
+	w.closed = true
+	close(w.done)
+	batch := w.take()
+	w.mu.Unlock()
+
+	return w.send(batch)
+}
+
+func (w *GELFHTTPWriter) flushLoop() {
+	ticker := time.NewTicker(w.opt.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.Flush()
+		}
+	}
+}
+
+// take takes the batch out of the writer, it must be called with the lock held.
+func (w *GELFHTTPWriter) take() []byte {
+	if w.count == 0 {
+		return nil
+	}
+
+	batch := w.batch.Bytes()
+	w.batch = bytes.Buffer{}
+	w.count = 0
+	return batch
+}
+
+// send sends the batch taken out of the writer, discarded even if it can't be sent.
+func (w *GELFHTTPWriter) send(batch []byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	encoding := ""
+	if w.compressor.compresses(len(batch)) {
+		encoding = w.encoding
+	}
+	return w.compressor.compress(batch, func(body []byte) error {
+		return w.post(body, encoding)
+	})
+}
+
+// post posts the body, retrying with an exponential backoff on 5xx statuses and network errors
+// until the writer is closed.
+func (w *GELFHTTPWriter) post(body []byte, encoding string) error {
+	backoff := w.opt.MinBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := w.request(body, encoding)
+		if err == nil || !retryable || retry >= w.opt.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-w.done:
+			timer.Stop()
+			return err
+		}
+		backoff = min(2*backoff, w.opt.MaxBackoff)
+	}
+}
+
+func (w *GELFHTTPWriter) request(body []byte, encoding string) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.opt.Header {
+		req.Header[k] = v
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+
+	resp, err := w.opt.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode >= 500, fmt.Errorf("gelf: %s: %s", w.url, resp.Status)
+}
@@ -0,0 +1,264 @@
+package logger_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+type gelfRequest struct {
+	path   string
+	header http.Header
+	body   string
+}
+
+// gelfServer records the requests and responds with the given statuses, then with 202 Accepted.
+type gelfServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []gelfRequest
+}
+
+func newGELFServer(t *testing.T, statuses ...int) *gelfServer {
+	s := &gelfServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body = gr
+		}
+		b, err := io.ReadAll(body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests = append(s.requests, gelfRequest{path: r.URL.Path, header: r.Header, body: string(b)})
+		status := http.StatusAccepted
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *gelfServer) bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bodies []string
+	for _, r := range s.requests {
+		bodies = append(bodies, r.body)
+	}
+	return bodies
+}
+
+func gelfMessage(message string) string {
+	return `{"version":"1.1","host":"localhost","timestamp":946684800,"level":6,"short_message":"` + message + `","_level_name":"INFO"}`
+}
+
+func TestGELFHTTPWriter(t *testing.T) {
+	srv := newGELFServer(t)
+	w, err := logger.NewGELFHTTPWriter(srv.URL, &logger.GELFHTTPOption{
+		Header:               http.Header{"Authorization": {"Bearer token"}},
+		Compression:          logger.GELFCompressionGzip,
+		CompressionThreshold: 200,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+	l.Info("short")
+	long := strings.Repeat("a", 200)
+	l.Info(long)
+
+	if len(srv.requests) != 2 {
+		t.Fatalf("got %d requests, expect 2", len(srv.requests))
+	}
+	for i, expected := range []struct {
+		body     string
+		encoding string
+	}{
+		{body: gelfMessage("short")},
+		{body: gelfMessage(long), encoding: "gzip"},
+	} {
+		r := srv.requests[i]
+		if r.path != "/gelf" {
+			t.Errorf("got path %s, expect /gelf", r.path)
+		}
+		if h := r.header.Get("Authorization"); h != "Bearer token" {
+			t.Errorf("got Authorization %q", h)
+		}
+		if h := r.header.Get("Content-Type"); h != "application/json" {
+			t.Errorf("got Content-Type %q", h)
+		}
+		if h := r.header.Get("Content-Encoding"); h != expected.encoding {
+			t.Errorf("got Content-Encoding %q, expect %q", h, expected.encoding)
+		}
+		if r.body != expected.body {
+			t.Errorf("\n   got: %s\nexpect: %s", r.body, expected.body)
+		}
+	}
+}
+
+func TestGELFHTTPWriterBatch(t *testing.T) {
+	srv := newGELFServer(t)
+	w, err := logger.NewGELFHTTPWriter(srv.URL+"/custom", &logger.GELFHTTPOption{BatchSize: 3, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+	for i := 0; i < 4; i++ {
+		l.Info(fmt.Sprint(i))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		gelfMessage("0") + "\n" + gelfMessage("1") + "\n" + gelfMessage("2"),
+		gelfMessage("3"),
+	}
+	if got := srv.bodies(); fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("\n   got: %q\nexpect: %q", got, expected)
+	}
+	if srv.requests[0].path != "/custom" {
+		t.Errorf("got path %s, expect /custom", srv.requests[0].path)
+	}
+}
+
+func TestGELFHTTPWriterFlushInterval(t *testing.T) {
+	srv := newGELFServer(t)
+	w, err := logger.NewGELFHTTPWriter(srv.URL, &logger.GELFHTTPOption{BatchSize: 10, FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true})).Info("message")
+
+	for deadline := time.Now().Add(5 * time.Second); len(srv.bodies()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the batch has not been sent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := srv.bodies(); len(got) != 1 || got[0] != gelfMessage("message") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestGELFHTTPWriterRetry(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		requests   int
+		err        string
+	}{
+		{
+			name:     "retried",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway},
+			requests: 3,
+		},
+		{
+			name:     "exhausted",
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			requests: 4,
+			err:      "500 Internal Server Error",
+		},
+		{
+			name:       "disabled",
+			statuses:   []int{http.StatusServiceUnavailable},
+			maxRetries: -1,
+			requests:   1,
+			err:        "503 Service Unavailable",
+		},
+		{
+			name:     "client error",
+			statuses: []int{http.StatusBadRequest},
+			requests: 1,
+			err:      "400 Bad Request",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newGELFServer(t, tc.statuses...)
+			w, err := logger.NewGELFHTTPWriter(srv.URL, &logger.GELFHTTPOption{MaxRetries: tc.maxRetries, MinBackoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			_, err = w.Write([]byte(gelfMessage("message") + "\n"))
+			if tc.err == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if tc.err != "" && (err == nil || !strings.HasSuffix(err.Error(), tc.err)) {
+				t.Errorf("got %v, expect %s", err, tc.err)
+			}
+			if got := len(srv.bodies()); got != tc.requests {
+				t.Errorf("got %d requests, expect %d", got, tc.requests)
+			}
+		})
+	}
+}
+
+func TestGELFHTTPWriterCloseDuringRetry(t *testing.T) {
+	srv := newGELFServer(t, http.StatusServiceUnavailable)
+	w, err := logger.NewGELFHTTPWriter(srv.URL, &logger.GELFHTTPOption{MinBackoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte(gelfMessage("retried") + "\n"))
+		errc <- err
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.bodies()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the request is not sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The writer's lock is not held while the request is retried.
+	if _, err := w.Write([]byte(gelfMessage("message") + "\n")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	select {
+	case err := <-errc:
+		if err == nil || !strings.HasSuffix(err.Error(), "503 Service Unavailable") {
+			t.Errorf("got %v, expect 503 Service Unavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close must interrupt the retries")
+	}
+}
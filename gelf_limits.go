@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"slices"
+	"unicode/utf8"
+)
+
+// A GELFLimits holds the size limits of a GELF payload, in bytes of encoded JSON (escape sequences included).
+// The names of the truncated or dropped fields are listed in the `_truncated' field so nothing is silently dropped,
+// the handlers/formatters reserving the `truncated' key when limits are set.
+// A zero limit disables it.
+type GELFLimits struct {
+	// ShortMessage is the maximum length of the short_message.
+	ShortMessage int
+	// FullMessage is the maximum length of the full_message.
+	FullMessage int
+	// FieldValue is the maximum length of the string values of the additional fields,
+	// the built-in ones (e.g. `_level_name') excepted.
+	FieldValue int
+	// Payload is the maximum size of the payload, without its trailing `\n'.
+	// The largest fields are truncated (or dropped when they are not strings) until the payload fits,
+	// the short_message keeping at least its first character.
+	// It is best-effort: a payload whose mandatory fields and `_truncated' field don't fit exceeds the limit.
+	// For the GELFUDPWriter, it should not exceed GELFMaxChunks chunks.
+	Payload int
+}
+
+const truncatedKey = "truncated"
+
+// SetLimits sets the size limits of the payload.
+func (b *BufferGELF) SetLimits(l GELFLimits) {
+	b.limits = l
+}
+
+// cut truncates the string value of the field to max bytes of JSON content,
+// returning false if the value is not a string. The field's name in the payload is its key with the given prefix.
+func (b *BufferGELF) cut(f *gelfField, max int, prefix string) bool {
+	bs := b.buf.Bytes()
+	if f.end-f.value < 2 || bs[f.value] != '"' {
+		return false
+	}
+
+	content := bs[f.value+1 : f.end-1]
+	if len(content) <= max {
+		return true
+	}
+
+	n := jsonStringCut(content, max)
+	removed := len(content) - n
+	b.splice(f.value+1+n, f.end-1, nil)
+	f.end -= removed
+	b.markTruncated(prefix + f.key)
+	return true
+}
+
+// fit truncates or drops the largest fields until the payload, with its closing brace and `_truncated' field, fits.
+func (b *BufferGELF) fit() {
+	for {
+		excess := b.buf.Len() + 1 + b.markerLen() - b.limits.Payload
+		if excess <= 0 {
+			return
+		}
+
+		f, i := b.largest()
+		if f == nil {
+			return
+		}
+
+		if i < 0 {
+			// The messages are kept since short_message is mandatory and must not be empty.
+			b.cut(f, max(f.end-f.value-2-excess, b.minContent(f)), "")
+			continue
+		}
+		if content := f.end - f.value - 2; content > excess && b.cut(f, content-excess, "_") {
+			continue
+		}
+		b.markTruncated("_" + f.key)
+		b.remove(i)
+	}
+}
+
+// largest returns the largest field that can be truncated with its index in the additional fields (-1 for the messages).
+func (b *BufferGELF) largest() (f *gelfField, i int) {
+	i = -1
+	for j := range b.fields {
+		if f == nil || b.fields[j].end-b.fields[j].start > f.end-f.start {
+			f, i = &b.fields[j], j
+		}
+	}
+
+	for _, m := range []*gelfField{&b.full, &b.short} {
+		if m.end-m.value-2 > b.minContent(m) && (f == nil || m.end-m.start > f.end-f.start) {
+			f, i = m, -1
+		}
+	}
+	return f, i
+}
+
+// minContent returns the minimum length of the JSON content of the message field,
+// the first character of the short_message.
+func (b *BufferGELF) minContent(m *gelfField) int {
+	if m != &b.short || m.end-m.value <= 2 {
+		return 0
+	}
+	return jsonStringUnit(b.buf.Bytes()[m.value+1 : m.end-1])
+}
+
+func (b *BufferGELF) markTruncated(name string) {
+	if !slices.Contains(b.truncated, name) {
+		b.truncated = append(b.truncated, name)
+	}
+}
+
+// markerLen returns the length of the `_truncated' field.
+func (b *BufferGELF) markerLen() int {
+	if len(b.truncated) == 0 {
+		return 0
+	}
+
+	n := len(`,"_":""`) + len(truncatedKey) + len(b.truncated) - 1
+	for _, name := range b.truncated {
+		n += len(name)
+	}
+	return n
+}
+
+// marker writes the `_truncated' field listing the truncated fields separated by commas.
+func (b *BufferGELF) marker() {
+	if len(b.truncated) == 0 {
+		return
+	}
+
+	b.fieldKey(truncatedKey)
+	b.buf.WriteByte('"')
+	for i, name := range b.truncated {
+		if i > 0 {
+			b.buf.WriteByte(',')
+		}
+		b.content(name, true)
+	}
+	b.buf.WriteByte('"')
+}
+
+// jsonStringCut returns the length of the longest prefix of the JSON string content not longer than max bytes
+// that doesn't split an escape sequence or a rune.
+func jsonStringCut(content []byte, max int) int {
+	i := 0
+	for i < len(content) {
+		n := jsonStringUnit(content[i:])
+		if i+n > max {
+			break
+		}
+		i += n
+	}
+	return i
+}
+
+// jsonStringUnit returns the length of the first escape sequence or rune of the non-empty JSON string content.
+func jsonStringUnit(content []byte) int {
+	switch c := content[0]; {
+	case c == '\\' && len(content) > 1 && content[1] == 'u':
+		return 6
+	case c == '\\':
+		return 2
+	case c >= utf8.RuneSelf:
+		_, n := utf8.DecodeRune(content)
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,115 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestBufferGELFLimits(t *testing.T) {
+	b := logger.NewBufferGELF()
+	defer b.Release()
+	b.SetLimits(logger.GELFLimits{ShortMessage: 5, FullMessage: 12, FieldValue: 4})
+
+	b.Add("short", "abcd")
+	b.Add("long", "abcdef")
+	b.Add("escaped", `a"bc`)
+	b.Add("unicode", "aéé")
+	b.Add("number", 123456789)
+	b.Message("message\nwith a stack")
+
+	expected := `{"version":"1.1","_short":"abcd","_long":"abcd","_escaped":"a\"b","_unicode":"aé",` +
+		`"_number":123456789,"short_message":"messa","full_message":"message\nwit",` +
+		`"_truncated":"_long,_escaped,_unicode,short_message,full_message"}`
+	if got := string(b.Complete(false)); got != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+}
+
+func TestBufferGELFPayloadLimit(t *testing.T) {
+	build := func(limit int) []byte {
+		b := logger.NewBufferGELF()
+		b.SetLimits(logger.GELFLimits{Payload: limit})
+
+		b.Add("stack", strings.Repeat("s", 100))
+		b.Add("number", 42)
+		b.Add("body", strings.Repeat("é", 20))
+		b.Message("message\n" + strings.Repeat("m", 50))
+		return bytes.Clone(b.Complete(false))
+	}
+	size := len(build(0))
+
+	for limit := 150; limit <= size+10; limit += 10 {
+		payload := build(limit)
+		if len(payload) > limit {
+			t.Errorf("limit %d: got %d bytes: %s", limit, len(payload), payload)
+		}
+		if !json.Valid(payload) {
+			t.Errorf("limit %d: invalid JSON: %s", limit, payload)
+		}
+
+		var m map[string]any
+		if err := json.Unmarshal(payload, &m); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m["short_message"]; !ok {
+			t.Errorf("limit %d: short_message must be kept: %s", limit, payload)
+		}
+		if _, ok := m["_truncated"]; ok != (limit < size) {
+			t.Errorf("limit %d: got _truncated %v: %s", limit, m["_truncated"], payload)
+		}
+	}
+}
+
+func TestSlogGELFLimits(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+		Deterministic: true,
+		Limits:        logger.GELFLimits{FieldValue: 50, Payload: 200},
+	})).With("attr", strings.Repeat("a", 60)).WithGroup("g")
+
+	l.Info("message", "body", strings.Repeat("b", 200))
+
+	// The body, truncated to 50 bytes, doesn't fit and is dropped, then the attr is truncated to fit in 200 bytes.
+	expected := `{"version":"1.1","_attr":"` + strings.Repeat("a", 45) + `","host":"localhost","timestamp":946684800,"level":6,"short_message":"message","_level_name":"INFO","_truncated":"_attr,_g.body"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestBufferGELFPayloadLimitShortMessage(t *testing.T) {
+	b := logger.NewBufferGELF()
+	defer b.Release()
+	b.SetLimits(logger.GELFLimits{Payload: 10})
+
+	b.Host("localhost")
+	b.Message("émessage\nwith a stack")
+
+	// The limit can't be honored, the short_message keeps its first character.
+	expected := `{"version":"1.1","host":"localhost","short_message":"é","full_message":"",` +
+		`"_truncated":"full_message,short_message"}`
+	if got := string(b.Complete(false)); got != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+}
+
+func TestSlogGELFLimitsReserved(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+		Deterministic: true,
+		Limits:        logger.GELFLimits{FieldValue: 3},
+	}))
+
+	l.Info("message", "truncated", "user", "body", "abcdef")
+
+	// The user's `truncated' field is renamed and the built-in `_level_name' is not truncated.
+	expected := `{"version":"1.1","_fields.truncated":"use","_body":"abc","host":"localhost","timestamp":946684800,"level":6,` +
+		`"short_message":"message","_level_name":"INFO","_truncated":"_fields.truncated,_body"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGELFChunkTimeout is the default delay after which the incomplete chunked messages are discarded, as Graylog.
+	DefaultGELFChunkTimeout = 5 * time.Second
+	// GELFMaxDecompressedSize is the maximum size of a payload decompressed by ParseGELF,
+	// so a small compressed payload can't exhaust the memory.
+	GELFMaxDecompressedSize = 16 << 20
+)
+
+var (
+	// ErrInvalidGELF is the error wrapped by the errors of ParseGELF and GELFMessage.Validate.
+	ErrInvalidGELF = errors.New("gelf: invalid payload")
+	// ErrGELFChunk is returned by ParseGELF for a chunk, which must be reassembled by a GELFDechunker.
+	ErrGELFChunk = errors.New("gelf: payload is a chunk")
+)
+
+type (
+	// A GELFMessage is a decoded GELF payload.
+	GELFMessage struct {
+		Version      string
+		Host         string
+		ShortMessage string
+		FullMessage  string
+		// Timestamp is the zero time when the payload has no timestamp.
+		Timestamp time.Time
+		// Level is the syslog severity, 1 (ALERT) when the payload has no level as specified by GELF.
+		Level int32
+		// Fields are the other fields by key (e.g. `_level_name'), the numbers being json.Number.
+		Fields map[string]any
+	}
+
+	// A GELFDechunker reassembles the chunked GELF payloads received over UDP.
+	// It is safe for concurrent use.
+	GELFDechunker struct {
+		// Timeout is the delay after which the incomplete messages are discarded.
+		// The default value is DefaultGELFChunkTimeout.
+		Timeout time.Duration
+
+		mu      sync.Mutex
+		pending map[[8]byte]*gelfChunks
+	}
+
+	gelfChunks struct {
+		first    time.Time
+		chunks   [][]byte
+		received int
+	}
+)
+
+// ParseGELF decodes the GELF payload p, decompressed when it is compressed with gzip or zlib.
+// A trailing `\n' or NUL byte is ignored.
+func ParseGELF(p []byte) (*GELFMessage, error) {
+	p, err := decompressGELF(p)
+	if err != nil {
+		return nil, err
+	}
+	if isGELFChunk(p) {
+		return nil, ErrGELFChunk
+	}
+	p = bytes.TrimRight(p, "\n\x00")
+
+	var fields map[string]any
+	d := json.NewDecoder(bytes.NewReader(p))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGELF, err)
+	}
+	if d.More() {
+		return nil, fmt.Errorf("%w: data after the JSON object", ErrInvalidGELF)
+	}
+
+	m := &GELFMessage{Level: 1, Fields: fields}
+	for key, dst := range map[string]*string{
+		"version":       &m.Version,
+		"host":          &m.Host,
+		"short_message": &m.ShortMessage,
+		"full_message":  &m.FullMessage,
+	} {
+		if v, ok := fields[key]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not a string", ErrInvalidGELF, key)
+			}
+			*dst = s
+			delete(fields, key)
+		}
+	}
+
+	if v, ok := fields["timestamp"]; ok {
+		f, err := gelfNumber(v).Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%w: timestamp is not a number", ErrInvalidGELF)
+		}
+		m.Timestamp = time.UnixMicro(int64(math.Round(f * 1e6))).UTC()
+		delete(fields, "timestamp")
+	}
+
+	if v, ok := fields["level"]; ok {
+		level, err := gelfNumber(v).Int64()
+		if err != nil {
+			return nil, fmt.Errorf("%w: level is not an integer", ErrInvalidGELF)
+		}
+		m.Level = int32(level)
+		delete(fields, "level")
+	}
+
+	return m, nil
+}
+
+// Validate checks that the message complies with the GELF 1.1 specification,
+// the returned error joins all the violations.
+func (m *GELFMessage) Validate() error {
+	var errs []error
+	invalid := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidGELF}, a...)...))
+	}
+
+	if m.Version != "1.1" {
+		invalid("version must be 1.1, got %q", m.Version)
+	}
+	if m.Host == "" {
+		invalid("host is missing")
+	}
+	if m.ShortMessage == "" {
+		invalid("short_message is missing")
+	}
+	if m.Level < 0 || m.Level > 7 {
+		invalid("level must be a syslog severity, got %d", m.Level)
+	}
+
+	for k, v := range m.Fields {
+		name, ok := strings.CutPrefix(k, "_")
+		switch {
+		case !ok:
+			invalid("field %q is not an additional field (prefixed by `_')", k)
+		case invalidFieldNameIndex(name) >= 0:
+			invalid("field %q has an invalid name", k)
+		case isGELFReserved(name):
+			invalid("field %q is reserved", k)
+		}
+
+		switch v.(type) {
+		case string, json.Number, float64, int, int64:
+		default:
+			invalid("field %q must be a string or a number, got %T", k, v)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Add adds a datagram received over UDP and returns the reassembled payload when it is complete, nil otherwise.
+// A datagram that is not a chunk is returned as is.
+func (d *GELFDechunker) Add(datagram []byte) ([]byte, error) {
+	if !isGELFChunk(datagram) {
+		return datagram, nil
+	}
+	if len(datagram) < gelfChunkHeaderSize {
+		return nil, fmt.Errorf("%w: truncated chunk header", ErrInvalidGELF)
+	}
+
+	var id [8]byte
+	copy(id[:], datagram[2:10])
+	seq, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > GELFMaxChunks || seq >= count {
+		return nil, fmt.Errorf("%w: chunk %d of %d", ErrInvalidGELF, seq, count)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.expire(now)
+
+	if d.pending == nil {
+		d.pending = make(map[[8]byte]*gelfChunks)
+	}
+	c, ok := d.pending[id]
+	if !ok {
+		c = &gelfChunks{first: now, chunks: make([][]byte, count)}
+		d.pending[id] = c
+	}
+	if len(c.chunks) != count {
+		delete(d.pending, id)
+		return nil, fmt.Errorf("%w: chunk count changed from %d to %d", ErrInvalidGELF, len(c.chunks), count)
+	}
+	if c.chunks[seq] == nil {
+		c.chunks[seq] = bytes.Clone(datagram[gelfChunkHeaderSize:])
+		c.received++
+	}
+	if c.received < count {
+		return nil, nil
+	}
+
+	delete(d.pending, id)
+	return bytes.Join(c.chunks, nil), nil
+}
+
+// expire discards the incomplete messages older than the timeout.
+func (d *GELFDechunker) expire(now time.Time) {
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = DefaultGELFChunkTimeout
+	}
+
+	for id, c := range d.pending {
+		if now.Sub(c.first) > timeout {
+			delete(d.pending, id)
+		}
+	}
+}
+
+func isGELFChunk(p []byte) bool {
+	return len(p) >= 2 && p[0] == gelfChunkMagic[0] && p[1] == gelfChunkMagic[1]
+}
+
+// decompressGELF decompresses the payload when it starts with the gzip or zlib magic bytes.
+func decompressGELF(p []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch {
+	case len(p) >= 2 && p[0] == 0x1f && p[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(p))
+	case len(p) >= 2 && p[0] == 0x78 && (uint16(p[0])<<8|uint16(p[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(p))
+	default:
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGELF, err)
+	}
+	defer r.Close()
+
+	p, err = io.ReadAll(io.LimitReader(r, GELFMaxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGELF, err)
+	}
+	if len(p) > GELFMaxDecompressedSize {
+		return nil, fmt.Errorf("%w: decompressed payload larger than %d bytes", ErrInvalidGELF, GELFMaxDecompressedSize)
+	}
+	return p, nil
+}
+
+// gelfNumber returns the number v or an empty json.Number.
+func gelfNumber(v any) json.Number {
+	n, _ := v.(json.Number)
+	return n
+}
@@ -0,0 +1,187 @@
+package logger_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestParseGELF(t *testing.T) {
+	for _, compression := range []logger.GELFCompression{logger.GELFCompressionNone, logger.GELFCompressionGzip, logger.GELFCompressionZlib} {
+		conn := listenUDP(t)
+		w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{Compression: compression})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+
+		l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+		l.Error("message\nwith a stack", "key", "value", "n", 42)
+
+		m, err := logger.ParseGELF(readDatagram(t, conn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Validate(); err != nil {
+			t.Error(err)
+		}
+
+		if m.Version != "1.1" || m.Host != "localhost" || m.Level != 3 || !m.Timestamp.Equal(logger.DeterministicTime) {
+			t.Errorf("compression %d: got %+v", compression, m)
+		}
+		if m.ShortMessage != "message" || m.FullMessage != "message\nwith a stack" {
+			t.Errorf("compression %d: got messages %q and %q", compression, m.ShortMessage, m.FullMessage)
+		}
+		if m.Fields["_key"] != "value" || m.Fields["_n"] != json.Number("42") || m.Fields["_level_name"] != "ERROR" || len(m.Fields) != 3 {
+			t.Errorf("compression %d: got fields %v", compression, m.Fields)
+		}
+	}
+
+	for _, payload := range []string{
+		`{"version":"1.1"`,
+		`{"version":1.1}`,
+		`{"timestamp":"now"}`,
+		`{"level":1.5}`,
+		`{} {}`,
+		`[]`,
+	} {
+		if _, err := logger.ParseGELF([]byte(payload)); !errors.Is(err, logger.ErrInvalidGELF) {
+			t.Errorf("%s: got %v, expect ErrInvalidGELF", payload, err)
+		}
+	}
+
+	if _, err := logger.ParseGELF([]byte{0x1e, 0x0f, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}); !errors.Is(err, logger.ErrGELFChunk) {
+		t.Errorf("got %v, expect ErrGELFChunk", err)
+	}
+
+	// Decompression bomb.
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(bytes.Repeat([]byte{' '}, logger.GELFMaxDecompressedSize+1))
+	zw.Close()
+	if _, err := logger.ParseGELF(bomb.Bytes()); !errors.Is(err, logger.ErrInvalidGELF) || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("got %v, expect a size error", err)
+	}
+}
+
+func TestGELFMessageValidate(t *testing.T) {
+	valid := func() *logger.GELFMessage {
+		return &logger.GELFMessage{
+			Version:      "1.1",
+			Host:         "localhost",
+			ShortMessage: "message",
+			Timestamp:    time.Now(),
+			Level:        6,
+			Fields:       map[string]any{"_key": "value", "_n": json.Number("42")},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(m *logger.GELFMessage)
+		reason string
+	}{
+		{"version", func(m *logger.GELFMessage) { m.Version = "1.0" }, "version"},
+		{"host", func(m *logger.GELFMessage) { m.Host = "" }, "host"},
+		{"short_message", func(m *logger.GELFMessage) { m.ShortMessage = "" }, "short_message"},
+		{"level", func(m *logger.GELFMessage) { m.Level = 8 }, "level"},
+		{"prefix", func(m *logger.GELFMessage) { m.Fields["key"] = "value" }, `"key"`},
+		{"name", func(m *logger.GELFMessage) { m.Fields["_a key"] = "value" }, `"_a key"`},
+		{"id", func(m *logger.GELFMessage) { m.Fields["_id"] = "value" }, `"_id"`},
+		{"source", func(m *logger.GELFMessage) { m.Fields["_source"] = "value" }, `"_source"`},
+		{"gl2", func(m *logger.GELFMessage) { m.Fields["_gl2_remote_ip"] = "value" }, `"_gl2_remote_ip"`},
+		{"bool", func(m *logger.GELFMessage) { m.Fields["_b"] = true }, `"_b"`},
+		{"object", func(m *logger.GELFMessage) { m.Fields["_o"] = map[string]any{} }, `"_o"`},
+	} {
+		m := valid()
+		tc.modify(m)
+		err := m.Validate()
+		if !errors.Is(err, logger.ErrInvalidGELF) || !strings.Contains(err.Error(), tc.reason) {
+			t.Errorf("%s: got %v", tc.name, err)
+		}
+	}
+}
+
+func TestGELFDechunker(t *testing.T) {
+	conn := listenUDP(t)
+	w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{
+		ChunkSize:   32,
+		Compression: logger.GELFCompressionGzip,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	message := strings.Repeat("0123456789", 20)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+	l.Info(message)
+
+	first := readDatagram(t, conn)
+	count := int(first[11])
+	chunks := [][]byte{first}
+	for len(chunks) < count {
+		chunks = append(chunks, readDatagram(t, conn))
+	}
+	if count < 2 {
+		t.Fatalf("got %d chunk, expect several", count)
+	}
+
+	var d logger.GELFDechunker
+	var payload []byte
+	for i := len(chunks) - 1; i >= 0; i-- { // Out of order.
+		p, err := d.Add(chunks[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && p != nil {
+			t.Fatalf("chunk %d: got a payload before all the chunks are received", i)
+		}
+		payload = p
+	}
+
+	m, err := logger.ParseGELF(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ShortMessage != message {
+		t.Errorf("got short_message %q", m.ShortMessage)
+	}
+
+	if p, err := d.Add([]byte(`{"version":"1.1"}`)); err != nil || string(p) != `{"version":"1.1"}` {
+		t.Errorf("a datagram that is not a chunk must be returned as is, got %s, %v", p, err)
+	}
+	if _, err := d.Add([]byte{0x1e, 0x0f, 0, 0, 0, 0, 0, 0, 0, 0, 2, 2}); !errors.Is(err, logger.ErrInvalidGELF) {
+		t.Errorf("got %v, expect ErrInvalidGELF for a sequence number out of range", err)
+	}
+}
+
+func TestGELFDechunkerTimeout(t *testing.T) {
+	d := logger.GELFDechunker{Timeout: 10 * time.Millisecond}
+	chunk := func(seq byte, data string) []byte {
+		return append([]byte{0x1e, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8, seq, 2}, data...)
+	}
+
+	if p, err := d.Add(chunk(0, `{"version":`)); p != nil || err != nil {
+		t.Fatalf("got %s, %v", p, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// The first chunk expired, so the message is incomplete.
+	if p, err := d.Add(chunk(1, `"1.1"}`)); p != nil || err != nil {
+		t.Fatalf("got %s, %v", p, err)
+	}
+	if p, err := d.Add(chunk(0, `{"version":`)); string(p) != `{"version":"1.1"}` || err != nil {
+		t.Errorf("got %s, %v", p, err)
+	}
+}
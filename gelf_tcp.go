@@ -0,0 +1,211 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGELFTCPBufferSize is the default size of the buffer holding the payloads while disconnected.
+	DefaultGELFTCPBufferSize = 1 << 20
+
+	defaultGELFTCPTimeout    = 5 * time.Second
+	defaultGELFTCPMinBackoff = 100 * time.Millisecond
+	defaultGELFTCPMaxBackoff = 30 * time.Second
+)
+
+// ErrGELFBufferFull is returned when a payload can't be buffered while disconnected.
+var ErrGELFBufferFull = errors.New("gelf: buffer full")
+
+type (
+	// A GELFTCPOption holds GELFTCPWriter's options.
+	GELFTCPOption struct {
+		// TLSConfig enables TLS when not nil.
+		TLSConfig *tls.Config
+		// DialTimeout is the timeout of the connections (5s by default).
+		DialTimeout time.Duration
+		// WriteTimeout is the deadline of each write (5s by default).
+		WriteTimeout time.Duration
+		// MinBackoff is the delay before the first reconnection attempt (100ms by default),
+		// doubled after each failed attempt up to MaxBackoff (30s by default).
+		MinBackoff time.Duration
+		MaxBackoff time.Duration
+		// BufferSize is the maximum number of bytes buffered while disconnected.
+		// The default value is DefaultGELFTCPBufferSize.
+		BufferSize int
+	}
+
+	// A GELFTCPWriter sends each written GELF payload, terminated by a NUL byte, to a Graylog GELF TCP input.
+	// When the connection is lost, the payloads are buffered until it is reestablished in background.
+	// It is safe for concurrent use.
+	GELFTCPWriter struct {
+		opt    GELFTCPOption
+		addr   string
+		mu     sync.Mutex
+		conn   net.Conn
+		frame  []byte
+		buf    bytes.Buffer // Payloads written while disconnected.
+		closed bool
+		done   chan struct{}
+	}
+)
+
+// NewGELFTCPWriter returns a new GELFTCPWriter connected to the given address (e.g. `graylog:12201').
+func NewGELFTCPWriter(addr string, o *GELFTCPOption) (*GELFTCPWriter, error) {
+	if o == nil {
+		o = &GELFTCPOption{}
+	}
+	if o.DialTimeout == 0 {
+		o.DialTimeout = defaultGELFTCPTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = defaultGELFTCPTimeout
+	}
+	if o.MinBackoff == 0 {
+		o.MinBackoff = defaultGELFTCPMinBackoff
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = defaultGELFTCPMaxBackoff
+	}
+	if o.BufferSize == 0 {
+		o.BufferSize = DefaultGELFTCPBufferSize
+	}
+
+	w := &GELFTCPWriter{
+		opt:  *o,
+		addr: addr,
+		done: make(chan struct{}),
+	}
+
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+
+	return w, nil
+}
+
+// Write sends the payload p, a GELF message usually terminated by a `\n' that is replaced by a NUL byte.
+// Each call to Write must contain exactly one GELF message.
+func (w *GELFTCPWriter) Write(p []byte) (int, error) {
+	payload := p
+	if n := len(payload); n > 0 && payload[n-1] == '\n' {
+		payload = payload[:n-1]
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+
+	w.frame = append(append(w.frame[:0], payload...), 0)
+
+	var err error
+	if w.conn != nil {
+		if _, err = w.write(w.conn, w.frame); err == nil {
+			return len(p), nil
+		}
+
+		// The whole payload is sent again on the next connection.
+		w.conn.Close()
+		w.conn = nil
+		go w.reconnect()
+	}
+
+	if w.buf.Len()+len(w.frame) > w.opt.BufferSize {
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrGELFBufferFull, err)
+		}
+		return 0, ErrGELFBufferFull
+	}
+	w.buf.Write(w.frame)
+	return len(p), nil
+}
+
+// Close closes the connection, the buffered payloads are discarded.
+func (w *GELFTCPWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return net.ErrClosed
+	}
+	w.closed = true
+	close(w.done)
+
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+func (w *GELFTCPWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.opt.DialTimeout}
+	if w.opt.TLSConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", w.addr, w.opt.TLSConfig)
+	}
+	return dialer.Dial("tcp", w.addr)
+}
+
+func (w *GELFTCPWriter) write(conn net.Conn, p []byte) (int, error) {
+	if err := conn.SetWriteDeadline(time.Now().Add(w.opt.WriteTimeout)); err != nil {
+		return 0, err
+	}
+	return conn.Write(p)
+}
+
+// reconnect reconnects with an exponential backoff and flushes the buffered payloads.
+func (w *GELFTCPWriter) reconnect() {
+	backoff := w.opt.MinBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-timer.C:
+		}
+
+		if conn, err := w.dial(); err == nil && w.flush(conn) {
+			return
+		}
+
+		backoff = min(2*backoff, w.opt.MaxBackoff)
+		timer.Reset(backoff)
+	}
+}
+
+// flush sends the buffered payloads on conn and uses it for the next writes when they are all sent.
+func (w *GELFTCPWriter) flush(conn net.Conn) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		conn.Close()
+		return true
+	}
+
+	b := w.buf.Bytes()
+	n, err := w.write(conn, b)
+	if err != nil {
+		// Only the payloads entirely sent are discarded.
+		if i := bytes.LastIndexByte(b[:n], 0); i >= 0 {
+			w.buf.Next(i + 1)
+		}
+		conn.Close()
+		return false
+	}
+
+	w.buf.Reset()
+	w.conn = conn
+	return true
+}
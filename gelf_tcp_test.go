@@ -0,0 +1,207 @@
+package logger_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+func listenTCP(t *testing.T) net.Listener {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// readFrames sends the NUL-terminated frames read from the accepted connections, numbered from 1.
+func readFrames(ln net.Listener) <-chan string {
+	frames := make(chan string, 1024)
+	go func() {
+		for i := 1; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(i int, conn net.Conn) {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				for {
+					frame, err := r.ReadString(0)
+					if err != nil {
+						return
+					}
+					frames <- fmt.Sprintf("%d %s", i, frame)
+				}
+			}(i, conn)
+		}
+	}()
+	return frames
+}
+
+func nextFrame(t *testing.T, frames <-chan string) string {
+	t.Helper()
+
+	select {
+	case frame := <-frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame received")
+		return ""
+	}
+}
+
+func TestGELFTCPWriter(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name   string
+		listen func(t *testing.T) net.Listener
+		option logger.GELFTCPOption
+	}{
+		{
+			name:   "tcp",
+			listen: listenTCP,
+		},
+		{
+			name: "tls",
+			listen: func(t *testing.T) net.Listener {
+				return tls.NewListener(listenTCP(t), srv.TLS)
+			},
+			option: logger.GELFTCPOption{TLSConfig: srv.Client().Transport.(*http.Transport).TLSClientConfig},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln := tc.listen(t)
+			frames := readFrames(ln)
+
+			w, err := logger.NewGELFTCPWriter(ln.Addr().String(), &tc.option)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+			l.Info("first")
+			l.Info("second")
+
+			for _, message := range []string{"first", "second"} {
+				expected := `1 {"version":"1.1","host":"localhost","timestamp":946684800,"level":6,"short_message":"` + message + `","_level_name":"INFO"}` + "\x00"
+				if got := nextFrame(t, frames); got != expected {
+					t.Errorf("\n   got: %q\nexpect: %q", got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGELFTCPWriterReconnect(t *testing.T) {
+	ln := listenTCP(t)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}()
+
+	w, err := logger.NewGELFTCPWriter(ln.Addr().String(), &logger.GELFTCPOption{MinBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// The first connection is lost.
+	(<-accepted).Close()
+
+	// Write until the connection loss is detected and the payloads are buffered.
+	var n int
+	for ; n < 100; n++ {
+		if _, err := fmt.Fprintf(w, "message %d\n", n); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	frames := readFrames(ln)
+	if _, err := fmt.Fprintf(w, "message %d\n", n); err != nil {
+		t.Fatal(err)
+	}
+
+	// The payloads written after the loss detection are sent in order on the new connection.
+	previous := -1
+	for previous != n {
+		frame := nextFrame(t, frames)
+
+		var i int
+		if _, err := fmt.Sscanf(frame, "1 message %d\x00", &i); err != nil {
+			t.Fatalf("%q: %s", frame, err)
+		}
+		if previous >= 0 && i != previous+1 {
+			t.Fatalf("got message %d after %d", i, previous)
+		}
+		previous = i
+	}
+}
+
+func TestGELFTCPWriterBufferFull(t *testing.T) {
+	ln := listenTCP(t)
+
+	// The server never reads.
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn
+	}()
+
+	w, err := logger.NewGELFTCPWriter(ln.Addr().String(), &logger.GELFTCPOption{
+		WriteTimeout: 50 * time.Millisecond,
+		MinBackoff:   time.Hour,
+		BufferSize:   1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	defer (<-accepted).Close()
+
+	payload := strings.Repeat("a", 64<<20) + "\n"
+	if _, err := w.Write([]byte(payload)); !errors.Is(err, logger.ErrGELFBufferFull) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("got %v, expect ErrGELFBufferFull and a deadline exceeded", err)
+	}
+
+	if _, err := w.Write([]byte(strings.Repeat("a", 1000) + "\n")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := w.Write([]byte(strings.Repeat("a", 100) + "\n")); !errors.Is(err, logger.ErrGELFBufferFull) {
+		t.Errorf("got %v, expect ErrGELFBufferFull", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := w.Write([]byte("message\n")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("got %v, expect net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+)
+
+const (
+	// GELFChunkSizeWAN is the chunk size fitting in the datagrams sent over the internet.
+	GELFChunkSizeWAN = 1420
+	// GELFChunkSizeLAN is the chunk size fitting in the datagrams sent over a local network with jumbo frames.
+	GELFChunkSizeLAN = 8154
+
+	// GELFMaxChunks is the maximum number of chunks of a GELF payload.
+	GELFMaxChunks = 128
+
+	gelfChunkHeaderSize = 12 // Magic bytes, message ID, sequence number and sequence count.
+)
+
+// gelfChunkMagic are the magic bytes starting a GELF chunk.
+var gelfChunkMagic = [2]byte{0x1e, 0x0f}
+
+// ErrGELFTooManyChunks is returned when a payload needs more than GELFMaxChunks chunks.
+var ErrGELFTooManyChunks = errors.New("gelf: payload needs too many chunks")
+
+type (
+	// A GELFUDPOption holds GELFUDPWriter's options.
+	GELFUDPOption struct {
+		// ChunkSize is the maximum size of a datagram, the bigger payloads are split in chunks.
+		// The default value is GELFChunkSizeWAN.
+		ChunkSize int
+		// Compression is the compression of the payloads, sent as is by default.
+		Compression GELFCompression
+		// CompressionLevel is the gzip/zlib compression level, the default level when zero.
+		CompressionLevel int
+		// CompressionThreshold is the size under which the payloads are sent uncompressed.
+		CompressionThreshold int
+	}
+
+	// A GELFUDPWriter sends each written GELF payload in UDP datagrams to a Graylog GELF UDP input.
+	// It is safe for concurrent use.
+	GELFUDPWriter struct {
+		opt        GELFUDPOption
+		conn       net.Conn
+		compressor *gelfCompressor
+		chunks     sync.Pool
+	}
+)
+
+// NewGELFUDPWriter returns a new GELFUDPWriter sending the payloads to the given address (e.g. `graylog:12201').
+func NewGELFUDPWriter(addr string, o *GELFUDPOption) (*GELFUDPWriter, error) {
+	if o == nil {
+		o = &GELFUDPOption{}
+	}
+	if o.ChunkSize == 0 {
+		o.ChunkSize = GELFChunkSizeWAN
+	}
+	if o.ChunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("gelf: chunk size must be greater than %d", gelfChunkHeaderSize)
+	}
+
+	compressor, err := newGELFCompressor(o.Compression, o.CompressionLevel, o.CompressionThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &GELFUDPWriter{
+		opt:        *o,
+		conn:       conn,
+		compressor: compressor,
+	}
+	w.chunks.New = func() any {
+		chunk := make([]byte, gelfChunkHeaderSize, w.opt.ChunkSize)
+		return &chunk
+	}
+	return w, nil
+}
+
+// Write sends the payload p, a GELF message usually terminated by a `\n' that is not sent.
+// Each call to Write must contain exactly one GELF message.
+func (w *GELFUDPWriter) Write(p []byte) (int, error) {
+	payload := p
+	if n := len(payload); n > 0 && payload[n-1] == '\n' {
+		payload = payload[:n-1]
+	}
+
+	if err := w.compressor.compress(payload, w.send); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the underlying connection.
+func (w *GELFUDPWriter) Close() error {
+	return w.conn.Close()
+}
+
+// send sends the payload in one datagram or in chunks when it doesn't fit.
+func (w *GELFUDPWriter) send(payload []byte) error {
+	if len(payload) <= w.opt.ChunkSize {
+		_, err := w.conn.Write(payload)
+		return err
+	}
+
+	size := w.opt.ChunkSize - gelfChunkHeaderSize
+	count := (len(payload) + size - 1) / size
+	if count > GELFMaxChunks {
+		return fmt.Errorf("%w: %d bytes in %d chunks", ErrGELFTooManyChunks, len(payload), count)
+	}
+
+	pchunk := w.chunks.Get().(*[]byte)
+	defer w.chunks.Put(pchunk)
+
+	chunk := (*pchunk)[:gelfChunkHeaderSize]
+	chunk[0], chunk[1] = gelfChunkMagic[0], gelfChunkMagic[1]
+	binary.BigEndian.PutUint64(chunk[2:10], rand.Uint64())
+	chunk[11] = byte(count)
+
+	for i := 0; i < count; i++ {
+		end := min((i+1)*size, len(payload))
+		chunk[10] = byte(i)
+		chunk = append(chunk[:gelfChunkHeaderSize], payload[i*size:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
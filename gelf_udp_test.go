@@ -0,0 +1,194 @@
+package logger_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p[:n]
+}
+
+func TestGELFUDPWriter(t *testing.T) {
+	conn := listenUDP(t)
+	w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+	l.Info("message", "key", "value")
+
+	expected := `{"version":"1.1","_key":"value","host":"localhost","timestamp":946684800,"level":6,"short_message":"message","_level_name":"INFO"}`
+	if got := readDatagram(t, conn); string(got) != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", got, expected)
+	}
+}
+
+func TestGELFUDPWriterChunks(t *testing.T) {
+	conn := listenUDP(t)
+	w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{ChunkSize: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	message := strings.Repeat("0123456789", 200)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+	l.Info(message)
+
+	var id []byte
+	var payload []byte
+	for seq, count := 0, 5; seq < count; seq++ {
+		chunk := readDatagram(t, conn)
+		if len(chunk) > 512 {
+			t.Fatalf("chunk %d: got %d bytes, expect at most 512", seq, len(chunk))
+		}
+		if chunk[0] != 0x1e || chunk[1] != 0x0f {
+			t.Fatalf("chunk %d: bad magic bytes %x", seq, chunk[:2])
+		}
+		if id == nil {
+			id = chunk[2:10]
+		}
+		if !bytes.Equal(chunk[2:10], id) {
+			t.Errorf("chunk %d: got message ID %x, expect %x", seq, chunk[2:10], id)
+		}
+		if int(chunk[10]) != seq || int(chunk[11]) != count {
+			t.Fatalf("chunk %d: got sequence %d/%d, expect %d/%d", seq, chunk[10], chunk[11], seq, count)
+		}
+		payload = append(payload, chunk[12:]...)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatalf("%s: %s", payload, err)
+	}
+	if m["short_message"] != message {
+		t.Errorf("got short_message %v", m["short_message"])
+	}
+	if payload[len(payload)-1] == '\n' {
+		t.Error("the trailing newline must not be sent")
+	}
+}
+
+func TestGELFUDPWriterTooManyChunks(t *testing.T) {
+	conn := listenUDP(t)
+	w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{ChunkSize: 13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := w.Write(bytes.Repeat([]byte{'a'}, logger.GELFMaxChunks+1)); !errors.Is(err, logger.ErrGELFTooManyChunks) {
+		t.Errorf("got %v, expect ErrGELFTooManyChunks", err)
+	}
+	if _, err := w.Write(bytes.Repeat([]byte{'a'}, logger.GELFMaxChunks)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if _, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{ChunkSize: 12}); err == nil {
+		t.Error("expect an error for a chunk size not greater than the chunk header")
+	}
+}
+
+func TestGELFUDPWriterCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		compression logger.GELFCompression
+		magic       []byte
+		reader      func(io.Reader) (io.Reader, error)
+	}{
+		{
+			name:        "gzip",
+			compression: logger.GELFCompressionGzip,
+			magic:       []byte{0x1f, 0x8b},
+			reader:      func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			name:        "zlib",
+			compression: logger.GELFCompressionZlib,
+			magic:       []byte{0x78},
+			reader:      func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := listenUDP(t)
+			w, err := logger.NewGELFUDPWriter(conn.LocalAddr().String(), &logger.GELFUDPOption{
+				Compression:          tc.compression,
+				CompressionThreshold: 200,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+
+			l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Deterministic: true}))
+
+			l.Info("short")
+			if got := readDatagram(t, conn); !bytes.HasPrefix(got, []byte(`{"version":"1.1"`)) {
+				t.Errorf("the payloads under the threshold must be sent uncompressed, got: %x", got)
+			}
+
+			message := strings.Repeat("a compressible message ", 20)
+			l.Info(message)
+			got := readDatagram(t, conn)
+			if !bytes.HasPrefix(got, tc.magic) {
+				t.Fatalf("got magic bytes %x, expect %x", got[:2], tc.magic)
+			}
+
+			r, err := tc.reader(bytes.NewReader(got))
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var m map[string]any
+			if err := json.Unmarshal(payload, &m); err != nil {
+				t.Fatalf("%s: %s", payload, err)
+			}
+			if m["short_message"] != message {
+				t.Errorf("got short_message %v", m["short_message"])
+			}
+		})
+	}
+
+	if _, err := logger.NewGELFUDPWriter("127.0.0.1:12201", &logger.GELFUDPOption{Compression: logger.GELFCompressionGzip, CompressionLevel: 42}); err == nil {
+		t.Error("expect an error for an invalid compression level")
+	}
+}
@@ -0,0 +1,427 @@
+// Package gelftest provides a local GELF server receiving the payloads of the GELF writers,
+// to assert them in tests or to print them in development without running Graylog.
+package gelftest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+type (
+	// An Option holds the configuration of a Server.
+	Option struct {
+		// UDP, TCP and HTTP are the addresses of the inputs (e.g. `127.0.0.1:12201'), an empty address disables the input.
+		UDP  string
+		TCP  string
+		HTTP string
+
+		// Handler handles the received messages converted by Record, e.g. a logger.SlogTextHandler to print them.
+		Handler slog.Handler
+
+		// Validate checks the received messages with logger.GELFMessage.Validate, the violations being sent to OnError.
+		Validate bool
+
+		// OnError is called with the errors of the inputs (e.g. an invalid payload).
+		OnError func(err error)
+	}
+
+	// A Server receives GELF payloads over UDP (chunked or not), TCP (NUL-terminated) and HTTP (`/gelf'),
+	// compressed or not, and collects the decoded messages.
+	Server struct {
+		opt    Option
+		udp    net.PacketConn
+		tcp    net.Listener
+		http   *http.Server
+		httpLn net.Listener
+		wg     sync.WaitGroup
+
+		mu       sync.Mutex
+		closed   bool
+		conns    map[net.Conn]struct{}
+		messages []*logger.GELFMessage
+		received chan struct{} // Closed and replaced when a message is received.
+	}
+)
+
+// NewServer starts a server listening on the inputs of the given options.
+func NewServer(o *Option) (*Server, error) {
+	if o == nil {
+		o = &Option{}
+	}
+
+	s := &Server{
+		opt:      *o,
+		conns:    make(map[net.Conn]struct{}),
+		received: make(chan struct{}),
+	}
+
+	if err := s.listen(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewTestServer starts a server listening on all the inputs of the loopback interface,
+// validating the received messages. The errors fail the test and the server is closed when the test ends.
+// The payloads that are not GELF 1.1 compliant (e.g. with logger.GELFEncoding.NativeBools) must be received
+// by a NewServer without Validate.
+func NewTestServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s, err := NewServer(&Option{
+		UDP:      "127.0.0.1:0",
+		TCP:      "127.0.0.1:0",
+		HTTP:     "127.0.0.1:0",
+		Validate: true,
+		OnError:  func(err error) { tb.Error(err) },
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { s.Close() })
+	return s
+}
+
+func (s *Server) listen() error {
+	var err error
+	if s.opt.UDP != "" {
+		if s.udp, err = net.ListenPacket("udp", s.opt.UDP); err != nil {
+			return err
+		}
+		s.serve(s.serveUDP)
+	}
+
+	if s.opt.TCP != "" {
+		if s.tcp, err = net.Listen("tcp", s.opt.TCP); err != nil {
+			return err
+		}
+		s.serve(s.serveTCP)
+	}
+
+	if s.opt.HTTP != "" {
+		if s.httpLn, err = net.Listen("tcp", s.opt.HTTP); err != nil {
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/gelf", s.serveHTTP)
+		s.http = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		s.serve(func() { s.http.Serve(s.httpLn) })
+	}
+
+	return nil
+}
+
+func (s *Server) serve(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// UDPAddr returns the address of the UDP input, empty when it is disabled.
+func (s *Server) UDPAddr() string {
+	if s.udp == nil {
+		return ""
+	}
+	return s.udp.LocalAddr().String()
+}
+
+// TCPAddr returns the address of the TCP input, empty when it is disabled.
+func (s *Server) TCPAddr() string {
+	if s.tcp == nil {
+		return ""
+	}
+	return s.tcp.Addr().String()
+}
+
+// URL returns the URL of the HTTP input, empty when it is disabled.
+func (s *Server) URL() string {
+	if s.httpLn == nil {
+		return ""
+	}
+	return "http://" + s.httpLn.Addr().String() + "/gelf"
+}
+
+// Messages returns the messages received so far, in the order of reception.
+func (s *Server) Messages() []*logger.GELFMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.messages)
+}
+
+// Wait waits until at least n messages are received and returns them.
+// It returns the messages received so far with an error after the timeout.
+func (s *Server) Wait(n int, timeout time.Duration) ([]*logger.GELFMessage, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		s.mu.Lock()
+		messages, received := slices.Clone(s.messages), s.received
+		s.mu.Unlock()
+
+		if len(messages) >= n {
+			return messages, nil
+		}
+
+		select {
+		case <-received:
+		case <-deadline.C:
+			return messages, fmt.Errorf("gelftest: got %d messages after %s, expect %d", len(messages), timeout, n)
+		}
+	}
+}
+
+// Reset discards the received messages.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = nil
+}
+
+// Close closes the inputs and their connections, then waits for the received payloads to be handled.
+func (s *Server) Close() error {
+	var errs []error
+	if s.udp != nil {
+		errs = append(errs, s.udp.Close())
+	}
+	if s.tcp != nil {
+		errs = append(errs, s.tcp.Close())
+	}
+	if s.http != nil {
+		errs = append(errs, s.http.Close())
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (s *Server) serveUDP() {
+	var dechunker logger.GELFDechunker
+	p := make([]byte, 65536)
+
+	for {
+		n, _, err := s.udp.ReadFrom(p)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.error(err)
+			}
+			return
+		}
+
+		payload, err := dechunker.Add(p[:n])
+		if err != nil {
+			s.error(err)
+			continue
+		}
+		if payload != nil {
+			s.receive(payload)
+		}
+	}
+}
+
+func (s *Server) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.error(err)
+			}
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.serve(func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+
+			r := bufio.NewReader(conn)
+			for {
+				frame, err := r.ReadBytes(0)
+				if len(frame) > 1 {
+					s.receive(frame)
+				}
+				if err != nil {
+					if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+						s.error(err)
+					}
+					return
+				}
+			}
+		})
+	}
+}
+
+// serveHTTP receives the payloads of the body, separated by `\n' when they are batched.
+// The body is limited to logger.GELFMaxDecompressedSize bytes, compressed or not.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	// The bodies are limited before and after decompression.
+	var body io.Reader = http.MaxBytesReader(w, r.Body, logger.GELFMaxDecompressedSize)
+	var err error
+	switch r.Header.Get("Content-Encoding") {
+	case "gzip":
+		body, err = gzip.NewReader(body)
+	case "deflate":
+		body, err = zlib.NewReader(body)
+	}
+	if err != nil {
+		s.error(err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p, err := io.ReadAll(io.LimitReader(body, logger.GELFMaxDecompressedSize+1))
+	if err == nil && len(p) > logger.GELFMaxDecompressedSize {
+		err = fmt.Errorf("gelftest: body larger than %d bytes", logger.GELFMaxDecompressedSize)
+	}
+	if err != nil {
+		s.error(err)
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	for _, payload := range bytes.Split(p, []byte{'\n'}) {
+		if len(payload) > 0 {
+			s.receive(payload)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) receive(payload []byte) {
+	m, err := logger.ParseGELF(payload)
+	if err != nil {
+		s.error(err)
+		return
+	}
+	if s.opt.Validate {
+		if err := m.Validate(); err != nil {
+			s.error(err)
+		}
+	}
+
+	s.mu.Lock()
+	s.messages = append(s.messages, m)
+	close(s.received)
+	s.received = make(chan struct{})
+	s.mu.Unlock()
+
+	if s.opt.Handler != nil {
+		r := Record(m)
+		if !s.opt.Handler.Enabled(context.Background(), r.Level) {
+			return
+		}
+		if err := s.opt.Handler.Handle(context.Background(), r); err != nil {
+			s.error(err)
+		}
+	}
+}
+
+func (s *Server) error(err error) {
+	if s.opt.OnError != nil {
+		s.opt.OnError(err)
+	}
+}
+
+// Record converts the message to a slog.Record, e.g. to print it with a logger.SlogTextHandler.
+// The time is the current time when the message has no timestamp, as Graylog.
+// The level is parsed from the `_level_name' field or converted from the syslog severity,
+// the additional fields are added as attributes without their `_' prefix, sorted by key,
+// after the `host' and the `full_message' when it differs from the short message.
+func Record(m *logger.GELFMessage) slog.Record {
+	level, err := logger.ParseSlogLevel(fmt.Sprint(m.Fields["_level_name"]))
+	if err != nil {
+		level = severityLevel(m.Level)
+	}
+
+	t := m.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	r := slog.NewRecord(t, level, m.ShortMessage, 0)
+	r.AddAttrs(slog.String("host", m.Host))
+	if m.FullMessage != "" && m.FullMessage != m.ShortMessage {
+		r.AddAttrs(slog.String("full_message", m.FullMessage))
+	}
+
+	keys := make([]string, 0, len(m.Fields))
+	for k := range m.Fields {
+		if k != "_level_name" {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		r.AddAttrs(slog.Any(strings.TrimPrefix(k, "_"), m.Fields[k]))
+	}
+
+	return r
+}
+
+// severityLevel returns the level of the syslog severity, the reverse of logger.SyslogSeverity.
+func severityLevel(severity int32) slog.Level {
+	switch {
+	case severity <= 0:
+		return logger.LevelEmergency
+	case severity == 1:
+		return logger.LevelAlert
+	case severity == 2:
+		return logger.LevelCritical
+	case severity == 3:
+		return slog.LevelError
+	case severity == 4:
+		return slog.LevelWarn
+	case severity == 5:
+		return logger.LevelNotice
+	case severity == 6:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
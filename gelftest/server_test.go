@@ -0,0 +1,187 @@
+package gelftest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/logger/gelftest"
+)
+
+func TestServer(t *testing.T) {
+	s := gelftest.NewTestServer(t)
+
+	udp, err := logger.NewGELFUDPWriter(s.UDPAddr(), &logger.GELFUDPOption{ChunkSize: 64, Compression: logger.GELFCompressionGzip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	tcp, err := logger.NewGELFTCPWriter(s.TCPAddr(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	httpw, err := logger.NewGELFHTTPWriter(s.URL(), &logger.GELFHTTPOption{BatchSize: 2, Compression: logger.GELFCompressionZlib})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpw.Close()
+
+	for _, tc := range []struct {
+		name string
+		w    io.Writer
+		n    int
+	}{
+		{"udp", udp, 1},
+		{"tcp", tcp, 1},
+		{"http", httpw, 2},
+	} {
+		s.Reset()
+
+		l := slog.New(logger.NewSlogGELFHandler(tc.w, &logger.SlogGELFOption{Deterministic: true})).With("input", tc.name)
+		for i := 0; i < tc.n; i++ {
+			l.Warn(strings.Repeat("message ", 20), "i", i)
+		}
+
+		messages, err := s.Wait(tc.n, 5*time.Second)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		for i, m := range messages {
+			if m.Level != 4 || m.Fields["_input"] != tc.name || m.Fields["_i"] != json.Number(string(rune('0'+i))) {
+				t.Errorf("%s: got %+v", tc.name, m)
+			}
+		}
+	}
+}
+
+func TestServerHandler(t *testing.T) {
+	w := new(bytes.Buffer)
+	s, err := gelftest.NewServer(&gelftest.Option{
+		UDP:     "127.0.0.1:0",
+		Handler: logger.NewSlogTextHandler(w, &logger.SlogTextOption{DisableColors: true, FullTimestamp: true}),
+		OnError: func(err error) { t.Error(err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	udp, err := logger.NewGELFUDPWriter(s.UDPAddr(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	l := slog.New(logger.NewSlogGELFHandler(udp, &logger.SlogGELFOption{Deterministic: true}))
+	l.Log(context.Background(), logger.LevelNotice, "message", "key", "value", "n", 42)
+
+	if _, err := s.Wait(1, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	s.Close() // Waits for the handler.
+
+	expected := `level=NOTICE time="2000-01-01T00:00:00Z" msg=message host=localhost key=value n=42` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	for _, tc := range []struct {
+		m     *logger.GELFMessage
+		level slog.Level
+	}{
+		{&logger.GELFMessage{Level: 3}, slog.LevelError},
+		{&logger.GELFMessage{Level: 7, Fields: map[string]any{"_level_name": "V2"}}, logger.LevelV(2)},
+		{&logger.GELFMessage{Level: 1, Fields: map[string]any{"_level_name": "custom"}}, logger.LevelAlert},
+	} {
+		if r := gelftest.Record(tc.m); r.Level != tc.level {
+			t.Errorf("%+v: got level %s, expect %s", tc.m, r.Level, tc.level)
+		}
+	}
+}
+
+func TestServerHTTPLimit(t *testing.T) {
+	var errs []error
+	var mu sync.Mutex
+	s, err := gelftest.NewServer(&gelftest.Option{
+		HTTP: "127.0.0.1:0",
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(bytes.Repeat([]byte{' '}, logger.GELFMaxDecompressedSize+1))
+	zw.Close()
+
+	req, err := http.NewRequest(http.MethodPost, s.URL(), &bomb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("got %s, expect 413", resp.Status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestServerNativeBools(t *testing.T) {
+	// The native booleans are not GELF 1.1 compliant, they are received by a server without Validate.
+	s, err := gelftest.NewServer(&gelftest.Option{
+		TCP:     "127.0.0.1:0",
+		OnError: func(err error) { t.Error(err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tcp, err := logger.NewGELFTCPWriter(s.TCPAddr(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	slog.New(logger.NewSlogGELFHandler(tcp, &logger.SlogGELFOption{Encoding: logger.GELFEncoding{NativeBools: true}})).Info("message", "ok", true)
+
+	messages, err := s.Wait(1, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages[0].Fields["_ok"] != true {
+		t.Errorf("got %+v", messages[0])
+	}
+	if err := messages[0].Validate(); err == nil {
+		t.Error("a validation error is expected")
+	}
+}
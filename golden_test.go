@@ -0,0 +1,68 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/logger/loggertest"
+)
+
+// go test -run Golden -update-golden
+
+func goldenScenario(h slog.Handler) {
+	l := logger.WrapSlogHandler(h)
+	l = l.WithPrefix("[uuid]")
+	l = l.WithFields(logger.M{"z": 1, "a": "with space", "m": true, "d": 42 * time.Millisecond})
+
+	l.Debug("debug")
+	l.Info("info")
+	l.WithPrefix("[sub]").WithField("key", "value").Warn("warn")
+	l.WithError(bytes.ErrTooLarge).Error("error")
+
+	sl := slog.New(h).With(logger.KeyPrefix, "[slog]").WithGroup("g")
+	sl.Info("record", slog.Duration("elapsed", time.Minute), slog.Group("sub", slog.Int("n", 42)))
+}
+
+func TestGoldenSlogText(t *testing.T) {
+	w := new(bytes.Buffer)
+	goldenScenario(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		Level:         slog.LevelDebug,
+		Deterministic: true,
+	}))
+	loggertest.Golden(t, "slog_text", w.Bytes())
+}
+
+func TestGoldenSlogTextFormatted(t *testing.T) {
+	w := new(bytes.Buffer)
+	goldenScenario(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		Level:           slog.LevelDebug,
+		ForceFormatting: true,
+		DisableColors:   true,
+		Deterministic:   true,
+	}))
+	loggertest.Golden(t, "slog_text_formatted", w.Bytes())
+}
+
+func TestGoldenSlogTextClock(t *testing.T) {
+	w := new(bytes.Buffer)
+	goldenScenario(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		Level:           slog.LevelDebug,
+		ForceFormatting: true,
+		DisableColors:   true,
+		Deterministic:   true,
+		Clock:           loggertest.NewClock(logger.DeterministicTime, 1500*time.Millisecond),
+	}))
+	loggertest.Golden(t, "slog_text_clock", w.Bytes())
+}
+
+func TestGoldenSlogGELF(t *testing.T) {
+	w := new(bytes.Buffer)
+	goldenScenario(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+		Level:         slog.LevelDebug,
+		Deterministic: true,
+	}))
+	loggertest.Golden(t, "slog_gelf", w.Bytes())
+}
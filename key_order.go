@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"cmp"
+	"strings"
+)
+
+// keyOrder returns the comparison function of the fields' keys or nil to keep the insertion order.
+// The priority keys come first in the given order, the other keys are ordered by compare,
+// alphabetically when sorted or by their insertion order otherwise.
+func keyOrder(sorted bool, priority []string, compare func(a, b string) int) func(a, b string) int {
+	if compare == nil && sorted {
+		compare = strings.Compare
+	}
+	if len(priority) == 0 {
+		return compare
+	}
+
+	ranks := make(map[string]int, len(priority))
+	for i, key := range priority {
+		if _, ok := ranks[key]; !ok {
+			ranks[key] = i
+		}
+	}
+
+	return func(a, b string) int {
+		ra, pa := ranks[a]
+		rb, pb := ranks[b]
+		switch {
+		case pa && pb:
+			return cmp.Compare(ra, rb)
+		case pa:
+			return -1
+		case pb:
+			return 1
+		case compare != nil:
+			return compare(a, b)
+		}
+		return 0
+	}
+}
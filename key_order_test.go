@@ -0,0 +1,80 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func reverse(a, b string) int {
+	return strings.Compare(b, a)
+}
+
+func TestSlogTextKeyOrder(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		option   logger.SlogTextOption
+		expected string
+	}{
+		{
+			name:     "insertion",
+			option:   logger.SlogTextOption{DisableSorting: true},
+			expected: "c=1 user=u a=1 d=1 request_id=r b=1",
+		},
+		{
+			name:     "priority",
+			option:   logger.SlogTextOption{PriorityKeys: []string{"request_id", "user"}},
+			expected: "request_id=r user=u a=1 b=1 c=1 d=1",
+		},
+		{
+			name:     "priority with insertion",
+			option:   logger.SlogTextOption{PriorityKeys: []string{"request_id", "user"}, DisableSorting: true},
+			expected: "request_id=r user=u c=1 a=1 d=1 b=1",
+		},
+		{
+			name:     "comparator",
+			option:   logger.SlogTextOption{KeyComparator: reverse, DisableSorting: true},
+			expected: "user=u request_id=r d=1 c=1 b=1 a=1",
+		},
+		{
+			name:     "priority with comparator",
+			option:   logger.SlogTextOption{PriorityKeys: []string{"user", "missing"}, KeyComparator: reverse},
+			expected: "user=u request_id=r d=1 c=1 b=1 a=1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.option.DisableTimestamp = true
+
+			w := new(bytes.Buffer)
+			l := slog.New(logger.NewSlogTextHandler(w, &tc.option)).With("c", 1, "user", "u").With("a", 1)
+			l.Info("message", "d", 1, "request_id", "r", "b", 1)
+
+			expected := "level=INFO msg=message " + tc.expected + "\n"
+			if w.String() != expected {
+				t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+			}
+		})
+	}
+}
+
+func TestLogrusTextKeyOrder(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusTextFormatter{
+		DisableTimestamp: true,
+		PriorityKeys:     []string{"request_id", "user"},
+		KeyComparator:    reverse,
+	})
+
+	ll.WithFields(logrus.Fields{"a": 1, "user": "u", "b": 1, "request_id": "r", "c": 1}).Info("message")
+
+	expected := "index=1 level=info msg=message request_id=r user=u c=1 b=1 a=1\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+	}
+}
@@ -0,0 +1,148 @@
+package logger_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+// exclusiveWriter is a writer not safe for concurrent use that reports the concurrent writes.
+type exclusiveWriter struct {
+	w          *bufio.Writer
+	buf        bytes.Buffer
+	writing    atomic.Bool
+	concurrent atomic.Int32
+}
+
+func newExclusiveWriter() *exclusiveWriter {
+	w := &exclusiveWriter{}
+	w.w = bufio.NewWriterSize(&w.buf, 64)
+	return w
+}
+
+func (w *exclusiveWriter) Write(p []byte) (int, error) {
+	if !w.writing.CompareAndSwap(false, true) {
+		w.concurrent.Add(1)
+	}
+	defer w.writing.Store(false)
+
+	// Yield in the middle of the write so the goroutines interleave even with a single CPU.
+	n, err := w.w.Write(p[:len(p)/2])
+	if err != nil {
+		return n, err
+	}
+	runtime.Gosched()
+	m, err := w.w.Write(p[len(p)/2:])
+	return n + m, err
+}
+
+func (w *exclusiveWriter) lines(t *testing.T) []string {
+	if err := w.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+}
+
+func hammer(h slog.Handler) {
+	var wg sync.WaitGroup
+	l := slog.New(h)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			l := l.With("goroutine", i).WithGroup("g")
+			for j := 0; j < 100; j++ {
+				l.Info("a message long enough to be split by the bufio writer", "n", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSlogTextConcurrentWrites(t *testing.T) {
+	w := newExclusiveWriter()
+	hammer(logger.NewSlogTextHandler(w, &logger.SlogTextOption{}))
+
+	if n := w.concurrent.Load(); n != 0 {
+		t.Errorf("got %d concurrent writes", n)
+	}
+
+	lines := w.lines(t)
+	if len(lines) != 1600 {
+		t.Fatalf("got %d lines, expect 1600", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "level=INFO ") || !strings.Contains(line, " g.n=") {
+			t.Fatalf("corrupted line: %q", line)
+		}
+	}
+}
+
+func TestSlogGELFConcurrentWrites(t *testing.T) {
+	w := newExclusiveWriter()
+	hammer(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname"}))
+
+	if n := w.concurrent.Load(); n != 0 {
+		t.Errorf("got %d concurrent writes", n)
+	}
+
+	lines := w.lines(t)
+	if len(lines) != 1600 {
+		t.Fatalf("got %d lines, expect 1600", len(lines))
+	}
+	for _, line := range lines {
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("corrupted line: %q: %s", line, err)
+		}
+	}
+}
+
+func TestSharedLocker(t *testing.T) {
+	w := newExclusiveWriter()
+	mu := new(sync.Mutex)
+
+	var wg sync.WaitGroup
+	for _, h := range []slog.Handler{
+		logger.NewSlogTextHandler(w, &logger.SlogTextOption{Locker: mu}),
+		logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname", Locker: mu}),
+	} {
+		wg.Add(1)
+		go func(h slog.Handler) {
+			defer wg.Done()
+			hammer(h)
+		}(h)
+	}
+	wg.Wait()
+
+	if n := w.concurrent.Load(); n != 0 {
+		t.Errorf("got %d concurrent writes", n)
+	}
+	if lines := w.lines(t); len(lines) != 3200 {
+		t.Errorf("got %d lines, expect 3200", len(lines))
+	}
+}
+
+func TestNopLocker(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{Locker: logger.NopLocker, DisableTimestamp: true}))
+
+	for i := 0; i < 3; i++ {
+		l.Info(fmt.Sprint(i))
+	}
+
+	expected := "level=INFO msg=0\nlevel=INFO msg=1\nlevel=INFO msg=2\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+	}
+}
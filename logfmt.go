@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// appendLogfmtKey writes a logfmt key.
+// The characters not allowed in a key (spaces, `=', `"' and control characters) are replaced by `_'.
+func appendLogfmtKey(b []byte, key string) []byte {
+	if key == "" {
+		return append(b, '_')
+	}
+
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			b = append(b, '_')
+			continue
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	return b
+}
+
+// logfmtText returns the text of a value.
+func logfmtText(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case error:
+		return value.Error()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// appendLogfmtValue writes a logfmt value, quoted and escaped when needed.
+// The output never contains a newline.
+func appendLogfmtValue(b []byte, s string, quoteEmpty bool) []byte {
+	if !logfmtNeedsQuoting(s) {
+		if s == "" && quoteEmpty {
+			return append(b, `""`...)
+		}
+		return append(b, s...)
+	}
+
+	b = append(b, '"')
+	b = appendLogfmtEscaped(b, s)
+	return append(b, '"')
+}
+
+// appendLogfmtEscaped writes s escaped as the content of a quoted logfmt value.
+func appendLogfmtEscaped(b []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if (r == utf8.RuneError && size == 1) || r == '\u2028' || r == '\u2029' || !unicode.IsPrint(r) {
+				b = append(b, s[start:i]...)
+				if r == utf8.RuneError {
+					b = append(b, "\ufffd"...)
+				} else {
+					b = append(b, '\\', 'u', hex[r>>12&0xF], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF])
+				}
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+
+		if c >= ' ' && c != '"' && c != '\\' && c != 0x7f {
+			i++
+			continue
+		}
+
+		b = append(b, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		default:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+		}
+		i++
+		start = i
+	}
+	return append(b, s[start:]...)
+}
+
+// logfmtNeedsQuoting reports whether the text must be quoted to be a logfmt value.
+func logfmtNeedsQuoting[T string | []byte](text T) bool {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= utf8.RuneSelf {
+			return logfmtNeedsQuotingRunes(string(text[i:]))
+		}
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func logfmtNeedsQuotingRunes(s string) bool {
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == '\u2028' || r == '\u2029' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package logger_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSlogTextStrictLogfmt(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		StrictLogfmt:     true,
+		QuoteCharacter:   "'",
+		QuoteEmptyFields: true,
+		DisableTimestamp: true,
+	}))
+
+	l.Info("multi\nline \"quoted\" \\ message",
+		"key with space", "v",
+		"k=v", "a=b",
+		"slice", []string{"a", "b"},
+		"err", errors.New("boom\r\n\tend"),
+		"empty", "",
+		"esc", "\x1b[31mred\u2028",
+		"plain", "value",
+		"utf8", "héllo",
+	)
+
+	expected := `level=INFO msg="multi\nline \"quoted\" \\ message" empty="" err="boom\r\n\tend" esc="\u001b[31mred\u2028" k_v="a=b" key_with_space=v plain=value slice="[a b]" utf8=héllo` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestLogrusTextStrictLogfmt(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusTextFormatter{StrictLogfmt: true, DisableTimestamp: true})
+
+	ll.WithField("k\"ey", "line1\nline2").WithField("n", 42).Info("a \"msg\"")
+
+	line := w.String()
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("one line is expected, got: %s", line)
+	}
+	if !strings.HasSuffix(line, `level=info msg="a \"msg\"" k_ey="line1\nline2" n=42`+"\n") {
+		t.Errorf("got: %s", line)
+	}
+}
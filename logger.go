@@ -11,6 +11,10 @@ type Logger interface {
 	WithField(key string, value any) Logger
 	WithError(error error) Logger
 	WithFields(fields map[string]any) Logger
+	// V returns a logger for the given verbosity (glog style) when enabled by
+	// the global/per-file verbosity thresholds (see SetVerbosity/SetVModule).
+	// Otherwise, a null logger is returned.
+	V(level int) Logger
 	//
 	Debug(args ...any)
 	Debugf(format string, args ...any)
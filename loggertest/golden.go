@@ -0,0 +1,61 @@
+// Package loggertest provides helpers to lock down log formats in tests.
+package loggertest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update-golden", false, "update the golden files of loggertest.Golden")
+
+// GoldenPath returns the path of the golden file for the given name.
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// Golden compares got with the content of the golden file `testdata/<name>.golden'.
+// When the `-update-golden' flag is set, the golden file is written with got instead.
+func Golden(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+
+	path := GoldenPath(name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("%s (run with -update-golden to create it)", err)
+	}
+
+	if !bytes.Equal(got, expected) {
+		tb.Errorf("%s mismatch (run with -update-golden to update it)\n   got:\n%s\nexpect:\n%s", path, got, expected)
+	}
+}
+
+// NewClock returns a clock starting at start and moving forward by step on each call.
+// It is safe for concurrent use.
+func NewClock(start time.Time, step time.Duration) func() time.Time {
+	var mu sync.Mutex
+	next := start
+
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		t := next
+		next = next.Add(step)
+		return t
+	}
+}
@@ -48,6 +48,15 @@ func (w *logruswrapper) WithFields(fields map[string]any) Logger {
 	}
 }
 
+// V returns the logger itself when the verbosity is enabled,
+// Logrus not supporting custom levels.
+func (w *logruswrapper) V(level int) Logger {
+	if !verbose(level, 1) {
+		return NewNullLogger()
+	}
+	return w
+}
+
 func (w *logruswrapper) Debug(args ...any) {
 	w.logrus.Debug(args...)
 }
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"sync"
@@ -13,6 +14,34 @@ import (
 type LogrusGELFFormatter struct {
 	sync.Once
 	Hostname string
+
+	// FieldClashes is the strategy applied to the fields clashing with the reserved keys
+	// (`host', `level_name', `file' when the caller is reported, `truncated' when Limits are set
+	// and the names reserved by Graylog like `id', `source' or `gl2_*'). The default value is FieldClashesPrefix.
+	FieldClashes FieldClashes
+
+	// ClashPrefix is the prefix of the fields renamed by FieldClashesPrefix.
+	// The default value is DefaultClashPrefix.
+	ClashPrefix string
+
+	// DuplicateKeys is the policy applied to the keys written several times.
+	// The default value is DuplicateKeysLastWins.
+	DuplicateKeys DuplicateKeys
+
+	// Limits holds the size limits of the payloads, the truncated fields being listed in a `_truncated' field.
+	Limits GELFLimits
+
+	// Encoding holds the encoding options of the field values (e.g. native booleans or flattened maps).
+	Encoding GELFEncoding
+
+	// InvalidFieldNames is the policy applied to the fields whose names don't match `^[\w\.\-]*$'
+	// (e.g. with spaces or unicode). The default value is InvalidFieldNamesReplace.
+	InvalidFieldNames InvalidFieldNames
+
+	// OnInvalidFieldName is called with the errors of InvalidFieldNamesReport.
+	OnInvalidFieldName func(err error)
+
+	clashes *clashResolver
 }
 
 func (f *LogrusGELFFormatter) init() {
@@ -24,14 +53,31 @@ func (f *LogrusGELFFormatter) init() {
 			f.Hostname = "localhost"
 		}
 	}
+
+	reserved := []string{"host", "level_name", "file"}
+	if f.Limits != (GELFLimits{}) {
+		reserved = append(reserved, truncatedKey)
+	}
+	f.clashes = newClashResolver(f.FieldClashes, f.ClashPrefix, reserved...)
+	f.clashes.isReserved = isGELFReserved
 }
 
 // Format implements logrus.Formatter.
 func (f *LogrusGELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	f.Do(f.init)
 	gelf := NewBufferGELF()
+	defer gelf.Release()
+	gelf.SetDuplicateKeys(f.DuplicateKeys)
+	gelf.SetInvalidFieldNames(f.InvalidFieldNames, f.OnInvalidFieldName)
+	gelf.SetEncoding(f.Encoding)
+	gelf.SetLimits(f.Limits)
 
-	for k, v := range entry.Data {
+	data, err := f.clashes.resolveData(entry.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range data {
 		if k == KeyPrefix {
 			entry.Message = fmt.Sprintf("%s %s", v, entry.Message)
 			continue
@@ -43,13 +89,18 @@ func (f *LogrusGELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	gelf.Timestamp(entry.Time)
 	gelf.Level(f.priorities(entry.Level))
 	gelf.Message(entry.Message)
-	gelf.Add("level_name", entry.Level.String())
+	gelf.builtin("level_name", entry.Level.String())
 
 	if entry.Caller != nil {
-		gelf.Add("file", entry.Caller.File)
+		gelf.builtin("file", entry.Caller.File)
 	}
 
-	return gelf.Complete(true), nil
+	// The payload is copied in the entry's buffer, pooled by Logrus, so the BufferGELF can be released.
+	if entry.Buffer == nil {
+		return bytes.Clone(gelf.Complete(true)), nil
+	}
+	entry.Buffer.Write(gelf.Complete(true))
+	return entry.Buffer.Bytes(), nil
 }
 
 func (f *LogrusGELFFormatter) priorities(level logrus.Level) int32 {
@@ -0,0 +1,80 @@
+package logger_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogrusGELFFieldClashes(t *testing.T) {
+	for _, tc := range []struct {
+		strategy logger.FieldClashes
+		expected string
+	}{
+		{strategy: logger.FieldClashesPrefix, expected: `^\{"version":"1\.1",("_fields\.fields\.level_name":"user",|"_fields\.level_name":"other",){2}"host".*"_level_name":"info"\}\n$`},
+		{strategy: logger.FieldClashesDrop, expected: `^\{"version":"1\.1","_fields\.level_name":"other","host".*"_level_name":"info"\}\n$`},
+		{strategy: logger.FieldClashesError, expected: `^$`},
+	} {
+		w := new(bytes.Buffer)
+		ll := logrus.New()
+		ll.SetOutput(w)
+		ll.SetFormatter(&logger.LogrusGELFFormatter{Hostname: "hostname", FieldClashes: tc.strategy})
+
+		ll.WithFields(logrus.Fields{"level_name": "user", "fields.level_name": "other"}).Info("message")
+		if !regexp.MustCompile(tc.expected).MatchString(w.String()) {
+			t.Errorf("strategy %d\n   got: %s\nexpect: %s", tc.strategy, w, tc.expected)
+		}
+	}
+}
+
+func TestLogrusGELFDuplicateKeys(t *testing.T) {
+	for _, tc := range []struct {
+		policy   logger.DuplicateKeys
+		expected string
+	}{
+		{policy: logger.DuplicateKeysLastWins, expected: `"_a.b_c":"underscore",`},
+		{policy: logger.DuplicateKeysFirstWins, expected: `"_a.b_c":"space",`},
+		{policy: logger.DuplicateKeysSuffix, expected: `"_a.b_c":"space","_a.b_c_2":"underscore",`},
+		{policy: logger.DuplicateKeysArray, expected: `"_a.b_c":["space","underscore"],`},
+	} {
+		w := new(bytes.Buffer)
+		ll := logrus.New()
+		ll.SetOutput(w)
+		ll.SetFormatter(&logger.LogrusGELFFormatter{
+			Hostname:      "hostname",
+			DuplicateKeys: tc.policy,
+			Encoding:      logger.GELFEncoding{Flatten: true},
+		})
+
+		// The flattened keys, sorted, collide once the invalid characters are replaced.
+		ll.WithField("a", map[string]string{"b c": "space", "b_c": "underscore"}).Info("message")
+		if !strings.Contains(w.String(), tc.expected) {
+			t.Errorf("policy %d\n   got: %s\nexpect: %s", tc.policy, w, tc.expected)
+		}
+		if n := strings.Count(w.String(), `"_a.b_c"`); n != 1 {
+			t.Errorf("policy %d: got %d _a.b_c", tc.policy, n)
+		}
+	}
+}
+
+func TestLogrusGELFLimits(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusGELFFormatter{Hostname: "hostname", Limits: logger.GELFLimits{FieldValue: 3}})
+
+	ll.WithField("truncated", "user").Info("message")
+
+	// The user's `truncated' field is renamed and the built-in `_level_name' is not truncated.
+	expected := `"_fields.truncated":"use",.*"_level_name":"info","_truncated":"_fields.truncated"\}\n$`
+	if !regexp.MustCompile(expected).MatchString(w.String()) {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+	if n := strings.Count(w.String(), `"_truncated"`); n != 1 {
+		t.Errorf("got %d _truncated", n)
+	}
+}
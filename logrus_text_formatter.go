@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
-	"sort"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -25,6 +25,15 @@ type LogrusTextFormatter struct {
 	// Force formatted layout, even for non-TTY output.
 	ForceFormatting bool
 
+	// Force sanitizing, even for non-TTY output.
+	// The sanitizing neutralizes the control characters, escape sequences and bidi overrides
+	// of the messages, keys and values so they can't be interpreted by the terminal.
+	// It is enabled by default for a TTY.
+	ForceSanitizing bool
+
+	// Force disabling sanitizing.
+	DisableSanitizing bool
+
 	// Disable timestamp logging. useful when output is redirected to logging
 	// system that already adds timestamps.
 	DisableTimestamp bool
@@ -41,9 +50,17 @@ type LogrusTextFormatter struct {
 
 	// The fields are sorted by default for a consistent output. For applications
 	// that log extremely frequently and don't use the JSON formatter this may not
-	// be desired.
+	// be desired. When disabled, the fields are written in the order of the entry's data map.
 	DisableSorting bool
 
+	// PriorityKeys are the keys of the fields written first, in the given order (e.g. `request_id', `user').
+	// The other fields are ordered according to KeyComparator and DisableSorting.
+	PriorityKeys []string
+
+	// KeyComparator orders the fields by their keys, like strings.Compare.
+	// It is used even if DisableSorting is set. The default order is alphabetical.
+	KeyComparator func(a, b string) int
+
 	// Wrap empty fields in quotes if true.
 	QuoteEmptyFields bool
 
@@ -51,6 +68,11 @@ type LogrusTextFormatter struct {
 	// with something else. For example: ', or `.
 	QuoteCharacter string
 
+	// Use strict logfmt for the non-formatted layout: the keys and values are escaped
+	// and quoted when needed so a record is parseable and fits on one line.
+	// QuoteCharacter is ignored in this mode.
+	StrictLogfmt bool
+
 	// Pad msg field with spaces on the right for display.
 	// The value for this parameter will be the size of padding.
 	// Its default value is zero, which means no padding will be applied for msg.
@@ -65,9 +87,20 @@ type LogrusTextFormatter struct {
 	// The default value is `%v'. You can use `%+v' to print the stacktrace of github.com/pkg/errors.
 	ValueFormatter string
 
+	// FieldClashes is the strategy applied to the fields clashing with the reserved keys
+	// (`index', `level', `time' and `msg'). The default value is FieldClashesPrefix.
+	FieldClashes FieldClashes
+
+	// ClashPrefix is the prefix of the fields renamed by FieldClashesPrefix.
+	// The default value is DefaultClashPrefix.
+	ClashPrefix string
+
 	// Color scheme to use.
 	colorScheme *compiledColorScheme
 
+	clashes *clashResolver
+	order   func(a, b string) int
+
 	// Whether the logger's out is to a terminal.
 	isTerminal bool
 
@@ -86,19 +119,27 @@ func (f *LogrusTextFormatter) SetColorScheme(colorScheme *ColorScheme) {
 
 // Format implements logrus.Formatter.
 func (f *LogrusTextFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.Do(func() { f.init(entry) })
+
 	if prefix, ok := entry.Data[KeyPrefix]; ok {
 		entry.Message = fmt.Sprintf("%s %s", prefix, entry.Message)
 		delete(entry.Data, KeyPrefix)
 	}
 
+	data, err := f.clashes.resolveData(entry.Data)
+	if err != nil {
+		return nil, err
+	}
+	entry.Data = data
+
 	keys := make([]string, 0, len(entry.Data))
 	for k := range entry.Data {
 		keys = append(keys, k)
 	}
 	lastKeyIdx := len(keys) - 1
 
-	if !f.DisableSorting {
-		sort.Strings(keys)
+	if f.order != nil {
+		slices.SortStableFunc(keys, f.order)
 	}
 
 	b := entry.Buffer
@@ -106,10 +147,6 @@ func (f *LogrusTextFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 		b = new(bytes.Buffer)
 	}
 
-	prefixFieldClashes(entry.Data)
-
-	f.Do(func() { f.init(entry) })
-
 	timestampFormat := f.TimestampFormat
 	if timestampFormat == "" {
 		timestampFormat = defaultTimestampFormat
@@ -178,7 +215,11 @@ func (f *LogrusTextFormatter) printColored(b *bytes.Buffer, entry *logrus.Entry,
 
 	level := levelColor(fmt.Sprintf("%5s", levelText))
 
+	sanitizing := f.sanitizing()
 	message := entry.Message
+	if sanitizing {
+		message = sanitize(message)
+	}
 	if f.PrefixRE != nil {
 		if f.PrefixRE.MatchString(message) {
 			match := f.PrefixRE.FindString(message)
@@ -205,10 +246,18 @@ func (f *LogrusTextFormatter) printColored(b *bytes.Buffer, entry *logrus.Entry,
 
 	for _, k := range keys {
 		v := entry.Data[k]
+		if sanitizing {
+			fmt.Fprintf(b, " %s=%s", levelColor(sanitize(k)), sanitize(fmt.Sprintf(f.ValueFormatter, v)))
+			continue
+		}
 		fmt.Fprintf(b, f.template, levelColor(k), v)
 	}
 }
 
+func (f *LogrusTextFormatter) sanitizing() bool {
+	return (f.ForceSanitizing || f.isTerminal) && !f.DisableSanitizing
+}
+
 func (f *LogrusTextFormatter) needsQuoting(text string) bool {
 	if f.QuoteEmptyFields && len(text) == 0 {
 		return true
@@ -226,9 +275,19 @@ func (f *LogrusTextFormatter) needsQuoting(text string) bool {
 }
 
 func (f *LogrusTextFormatter) appendKeyValue(b *bytes.Buffer, key string, value any, appendSpace bool) {
-	b.WriteString(key)
-	b.WriteByte('=')
-	f.appendValue(b, value)
+	if f.sanitizing() {
+		key = sanitize(key)
+	}
+
+	if f.StrictLogfmt {
+		b.Write(appendLogfmtKey(b.AvailableBuffer(), key))
+		b.WriteByte('=')
+		b.Write(appendLogfmtValue(b.AvailableBuffer(), logfmtText(value), f.QuoteEmptyFields))
+	} else {
+		b.WriteString(key)
+		b.WriteByte('=')
+		f.appendValue(b, value)
+	}
 
 	if appendSpace {
 		b.WriteByte(' ')
@@ -236,6 +295,17 @@ func (f *LogrusTextFormatter) appendKeyValue(b *bytes.Buffer, key string, value
 }
 
 func (f *LogrusTextFormatter) appendValue(b *bytes.Buffer, value any) {
+	if f.sanitizing() {
+		switch v := value.(type) {
+		case string:
+			value = sanitize(v)
+		case error:
+			value = sanitize(v.Error())
+		default:
+			value = sanitize(fmt.Sprint(v))
+		}
+	}
+
 	switch value := value.(type) {
 	case string:
 		if !f.needsQuoting(value) {
@@ -264,6 +334,8 @@ func (f *LogrusTextFormatter) init(entry *logrus.Entry) {
 		f.ValueFormatter = "%v"
 	}
 	f.template = " %s=" + f.ValueFormatter
+	f.order = keyOrder(!f.DisableSorting, f.PriorityKeys, f.KeyComparator)
+	f.clashes = newClashResolver(f.FieldClashes, f.ClashPrefix, "index", "level", "time", "msg")
 
 	if entry.Logger != nil {
 		f.isTerminal = checkIfTerminal(entry.Logger.Out)
@@ -27,6 +27,10 @@ func (w *null) WithFields(_ map[string]any) Logger {
 	return w
 }
 
+func (w *null) V(_ int) Logger {
+	return w
+}
+
 func (w *null) Debug(_ ...any) {
 }
 
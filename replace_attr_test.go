@@ -0,0 +1,115 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	switch {
+	case groups == nil && a.Key == slog.TimeKey:
+		return slog.Attr{} // Drop
+	case groups == nil && a.Key == slog.LevelKey:
+		a.Key = "severity"
+	case groups == nil && a.Key == slog.MessageKey:
+		a.Value = slog.StringValue(strings.ToUpper(a.Value.String()))
+	case groups == nil && a.Key == slog.SourceKey:
+		src := a.Value.Any().(*slog.Source)
+		src.File = "file.go"
+		src.Line = 42
+	case a.Key == "secret":
+		a.Value = slog.StringValue("***")
+	case len(groups) > 0 && a.Key == "drop":
+		return slog.Attr{}
+	case a.Key == "groups":
+		a.Value = slog.StringValue(strings.Join(groups, ">"))
+	}
+	return a
+}
+
+func replaceAttrScenario(h slog.Handler) {
+	l := slog.New(h).With("secret", "s3cr3t", "groups", "").WithGroup("g1").With("drop", 1, "groups", "")
+	l.Info("message",
+		slog.Group("g2", slog.String("groups", ""), slog.Int("drop", 2), slog.String("secret", "s3cr3t")),
+		slog.Group("", slog.String("inlined", "v")),
+		slog.Group("empty"),
+	)
+}
+
+func TestSlogTextReplaceAttr(t *testing.T) {
+	w := new(bytes.Buffer)
+	replaceAttrScenario(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		ReplaceAttr: replaceAttr,
+		AddSource:   true,
+	}))
+
+	expected := `severity=INFO msg=MESSAGE g1.g2.groups="g1>g2" g1.g2.secret="***" g1.groups=g1 g1.inlined=v groups= secret="***" source="file.go:42"` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+
+	//
+
+	w.Reset()
+	replaceAttrScenario(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		ReplaceAttr:     replaceAttr,
+		ForceFormatting: true,
+	}))
+
+	expected = ` INFO MESSAGE g1.g2.groups=g1>g2 g1.g2.secret=*** g1.groups=g1 g1.inlined=v groups= secret=***` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestSlogGELFReplaceAttr(t *testing.T) {
+	w := new(bytes.Buffer)
+	replaceAttrScenario(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+		Hostname:    "hostname-42",
+		ReplaceAttr: replaceAttr,
+		AddSource:   true,
+	}))
+
+	expected := `{"version":"1.1","_secret":"***","_groups":"","_g1.groups":"g1","_g1.g2.groups":"g1\u003eg2","_g1.g2.secret":"***","_g1.inlined":"v","_file":"file.go","_line":42,"host":"hostname-42","level":6,"short_message":"MESSAGE","_level_name":"INFO"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestSlogAddSource(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := logger.WrapSlogHandler(logger.NewSlogTextHandler(w, &logger.SlogTextOption{AddSource: true}))
+
+	l.Info("info")
+	l.Printf("%s", "printf")
+	l.Println("println")
+	l.WithField("k", "v").Print("print")
+
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got: %s", w)
+	}
+	source := regexp.MustCompile(`source=\S+/replace_attr_test\.go:\d+`)
+	for _, line := range lines {
+		if !source.MatchString(line) {
+			t.Errorf("got: %s", line)
+		}
+	}
+
+	//
+
+	w.Reset()
+	l = logger.WrapSlogHandler(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{AddSource: true, Clock: func() time.Time { return time.Unix(42, 0) }}))
+	l.Info("info")
+
+	expected := regexp.MustCompile(`"_file":"\S+/replace_attr_test\.go","_line":\d+,.*"timestamp":42,`)
+	if !expected.MatchString(w.String()) {
+		t.Errorf("got: %s", w)
+	}
+}
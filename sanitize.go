@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"unicode/utf8"
+)
+
+// sanitize neutralizes the control characters (including ESC, so the CSI/OSC sequences are not interpreted),
+// the invalid UTF-8 bytes, the line separators and the bidi overrides by replacing them with their escaped form
+// (e.g. `\x1b', `\n' or `\u202e'). Tabs are kept as is.
+func sanitize(s string) string {
+	if sanitizeIndex(s) == len(s) {
+		return s // Fast path, nothing to sanitize.
+	}
+	return string(appendSanitized(make([]byte, 0, len(s)+16), s))
+}
+
+// appendSanitized appends the sanitized s to b (see sanitize).
+func appendSanitized(b []byte, s string) []byte {
+	i := sanitizeIndex(s)
+	b = append(b, s[:i]...)
+
+	for i < len(s) {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if unsafeASCII(c) {
+				switch c {
+				case '\n':
+					b = append(b, `\n`...)
+				case '\r':
+					b = append(b, `\r`...)
+				default:
+					b = append(b, '\\', 'x', hex[c>>4], hex[c&0xF])
+				}
+			} else {
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, '\\', 'x', hex[c>>4], hex[c&0xF])
+		case unsafeRune(r, size):
+			b = append(b, '\\', 'u', hex[r>>12&0xF], hex[r>>8&0xF], hex[r>>4&0xF], hex[r&0xF])
+		default:
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+
+	return b
+}
+
+// sanitizeIndex returns the index of the first character to sanitize or len(s).
+func sanitizeIndex(s string) int {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if unsafeASCII(c) {
+				return i
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unsafeRune(r, size) {
+			return i
+		}
+		i += size
+	}
+	return i
+}
+
+func unsafeASCII(c byte) bool {
+	return (c < ' ' && c != '\t') || c == 0x7f
+}
+
+func unsafeRune(r rune, size int) bool {
+	switch {
+	case r == utf8.RuneError && size == 1: // Invalid byte, e.g. a raw 8-bit CSI (0x9b).
+		return true
+	case r >= 0x80 && r <= 0x9f: // C1 controls.
+		return true
+	case r == '\u061c', r == '\u200e', r == '\u200f': // Bidi marks.
+		return true
+	case r >= '\u202a' && r <= '\u202e': // Bidi embeddings/overrides.
+		return true
+	case r >= '\u2066' && r <= '\u2069': // Bidi isolates.
+		return true
+	case r == '\u2028', r == '\u2029': // Line/paragraph separators.
+		return true
+	}
+	return false
+}
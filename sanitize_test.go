@@ -0,0 +1,81 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"regexp"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSlogTextSanitizing(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		ForceFormatting:  true,
+		ForceColors:      true,
+		ForceSanitizing:  true,
+		DisableTimestamp: true,
+		PrefixRE:         regexp.MustCompile(`^(\[.*?\])\s`),
+	})).With(logger.KeyPrefix, "[p]")
+
+	l.Info("fake\n[0000]  INFO injected \x1b]0;title\x07\x1b[2J",
+		"k\x1b[31m", "v\u202egnp.exe\r",
+		"tab", "a\tb",
+		"raw", string([]byte{0x9b, '3', '1', 'm'}),
+		"c1", "\u009b31m",
+	)
+
+	expected := "\x1b[0;32m INFO\x1b[0m \x1b[0;36m[p] \x1b[0mfake\\n[0000]  INFO injected \\x1b]0;title\\x07\\x1b[2J" +
+		" \x1b[0;32mc1\x1b[0m=\\u009b31m" +
+		" \x1b[0;32mk\\x1b[31m\x1b[0m=v\\u202egnp.exe\\r" +
+		" \x1b[0;32mraw\x1b[0m=\\x9b31m" +
+		" \x1b[0;32mtab\x1b[0m=a\tb\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+	}
+
+	//
+
+	w.Reset()
+	l = slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{ForceSanitizing: true, DisableTimestamp: true}))
+	l.Info("a\x1b[2Jb")
+	if w.String() != "level=INFO msg=\"a\\x1b[2Jb\"\n" {
+		t.Errorf("got: %q", w)
+	}
+
+	w.Reset()
+	l.Info("m", "k\x1b[2J\nfake=1", "v")
+	if w.String() != "level=INFO msg=m k\\x1b[2J\\nfake=1=v\n" {
+		t.Errorf("the keys must be sanitized, got: %q", w)
+	}
+
+	//
+
+	w.Reset()
+	l = slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{ForceFormatting: true, DisableTimestamp: true}))
+	l.Info("a\x1b[2Jb")
+	if w.String() != " INFO a\x1b[2Jb\n" {
+		t.Errorf("non-TTY output must not be sanitized by default, got: %q", w)
+	}
+}
+
+func TestLogrusTextSanitizing(t *testing.T) {
+	w := new(bytes.Buffer)
+	ll := logrus.New()
+	ll.SetOutput(w)
+	ll.SetFormatter(&logger.LogrusTextFormatter{ForceFormatting: true, ForceSanitizing: true, DisableTimestamp: true})
+
+	ll.WithField("k", "\x1b[31mred").Info("line1\nline2")
+	if w.String() != " INFO line1\\nline2 k=\\x1b[31mred\n" {
+		t.Errorf("got: %q", w)
+	}
+
+	w.Reset()
+	ll.SetFormatter(&logger.LogrusTextFormatter{ForceSanitizing: true, DisableTimestamp: true})
+	ll.WithField("k\x1b[2J\nfake=1", "v").Info("m")
+	if w.String() != "index=1 level=info msg=m k\\x1b[2J\\nfake=1=v\n" {
+		t.Errorf("the keys must be sanitized, got: %q", w)
+	}
+}
@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A LevelSignalOption holds HandleLevelSignals' options.
+type LevelSignalOption struct {
+	// Levels are the level vars changed by the signals.
+	// If not defined, they are discovered from the logger's handler
+	// (see HandleLevelSignals).
+	Levels []*slog.LevelVar
+
+	// Routers are the level routers changed by the signals.
+	// The debug mode replaces their spec by the debug level and then restores it,
+	// the cycle changes only their default level and keeps the per-prefix levels.
+	// The levels of their wrapped handlers are not changed, they must be low enough (e.g. DEBUG).
+	Routers []*SlogLevelRouter
+
+	// Timeout is the duration of the temporary debug mode.
+	// The default value is 5 minutes.
+	Timeout time.Duration
+
+	// Cycle is the sequence of levels cycled through.
+	// The default value is DEBUG, INFO, WARN and ERROR.
+	Cycle []slog.Level
+}
+
+type levelSignaler struct {
+	mu      sync.Mutex
+	logger  Logger
+	levels  []*slog.LevelVar
+	routers []*SlogLevelRouter
+	timeout time.Duration
+	cycle   []slog.Level
+
+	timer      *time.Timer
+	generation int // Incremented by each timer so a stale timeout doesn't end an extended debug mode.
+	saved      []slog.Level
+	savedSpecs []string
+}
+
+// HandleLevelSignals installs the signal handlers changing the levels at runtime:
+//   - SIGUSR1 drops all the levels to debug for the configured timeout, then reverts them
+//   - SIGUSR2 cycles through the levels (and cancels the temporary debug mode)
+//
+// Each change is logged with l. When no level/router is given in the options, they are discovered
+// from l if it is built on WrapSlogHandler with a SlogTextHandler/SlogGELFHandler whose level
+// is a *slog.LevelVar, or with a SlogLevelRouter.
+//
+// The returned function uninstalls the signal handlers and reverts any temporary debug mode.
+// On platforms without SIGUSR1/SIGUSR2 (e.g. Windows), no signal handler is installed.
+func HandleLevelSignals(l Logger, o *LevelSignalOption) (stop func()) {
+	s := newLevelSignaler(l, o)
+	if debugSignal == nil {
+		return func() {}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, debugSignal, cycleSignal)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-c:
+				if sig == debugSignal {
+					s.debug()
+					continue
+				}
+				s.next()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		close(done)
+		s.restore()
+	}
+}
+
+func newLevelSignaler(l Logger, o *LevelSignalOption) *levelSignaler {
+	if o == nil {
+		o = &LevelSignalOption{}
+	}
+
+	s := &levelSignaler{
+		logger:  l,
+		levels:  o.Levels,
+		routers: o.Routers,
+		timeout: o.Timeout,
+		cycle:   o.Cycle,
+	}
+
+	if len(s.levels) == 0 && len(s.routers) == 0 {
+		if h, ok := UnwrapSlogHandler(l); ok {
+			s.discover(h)
+		}
+	}
+	if s.timeout <= 0 {
+		s.timeout = 5 * time.Minute
+	}
+	if len(s.cycle) == 0 {
+		s.cycle = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	}
+
+	return s
+}
+
+func (s *levelSignaler) discover(h slog.Handler) {
+	switch h := h.(type) {
+	case *SlogTextHandler:
+		if lvl, ok := h.opt.Level.(*slog.LevelVar); ok {
+			s.levels = append(s.levels, lvl)
+		}
+	case *SlogGELFHandler:
+		if lvl, ok := h.opt.Level.(*slog.LevelVar); ok {
+			s.levels = append(s.levels, lvl)
+		}
+	case *SlogLevelRouter:
+		s.routers = append(s.routers, h)
+	}
+}
+
+// debug drops the levels to debug until the timeout, extending the timeout when already in debug mode.
+func (s *levelSignaler) debug() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.start()
+		s.logger.Warnf("[logger] debug mode extended for %s", s.timeout)
+		return
+	}
+
+	s.saved = make([]slog.Level, len(s.levels))
+	for i, lvl := range s.levels {
+		s.saved[i] = lvl.Level()
+	}
+	s.savedSpecs = make([]string, len(s.routers))
+	for i, r := range s.routers {
+		s.savedSpecs[i] = r.Spec()
+	}
+
+	for _, lvl := range s.levels {
+		lvl.Set(slog.LevelDebug)
+	}
+	for _, r := range s.routers {
+		r.SetSpec(DefaultLevelNames.Name(slog.LevelDebug)) // Always a valid spec.
+	}
+
+	s.start()
+	s.logger.Warnf("[logger] debug mode enabled for %s", s.timeout)
+}
+
+// start starts the timer ending the debug mode.
+func (s *levelSignaler) start() {
+	s.generation++
+	generation := s.generation
+	s.timer = time.AfterFunc(s.timeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The timer may have fired while the debug mode was extended or canceled.
+		if generation == s.generation {
+			s.restoreLocked()
+		}
+	})
+}
+
+// restore reverts the levels changed by the debug mode.
+func (s *levelSignaler) restore() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.restoreLocked()
+}
+
+func (s *levelSignaler) restoreLocked() {
+	if s.timer == nil {
+		return
+	}
+	s.timer.Stop()
+	s.timer = nil
+	s.generation++
+
+	// Logged before restoring so the message is visible with the debug levels.
+	s.logger.Warn("[logger] debug mode disabled, restoring levels")
+	for i, lvl := range s.levels {
+		lvl.Set(s.saved[i])
+	}
+	for i, r := range s.routers {
+		r.SetSpec(s.savedSpecs[i]) // Already validated.
+	}
+}
+
+// next sets the level following the current one in the cycle.
+func (s *levelSignaler) next() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := slog.LevelInfo
+	switch {
+	case len(s.levels) > 0:
+		current = s.levels[0].Level()
+	case len(s.routers) > 0:
+		current = s.routers[0].level()
+	}
+
+	if s.timer != nil {
+		// Cycling cancels the debug mode without restoring the levels,
+		// only the per-prefix levels of the routers are restored.
+		s.timer.Stop()
+		s.timer = nil
+		s.generation++
+		for i, r := range s.routers {
+			r.SetSpec(s.savedSpecs[i]) // Already validated.
+		}
+	}
+
+	level := s.cycle[0]
+	for _, l := range s.cycle {
+		if l > current {
+			level = l
+			break
+		}
+	}
+
+	// The change is logged with the lowest of the two levels so the message is visible.
+	if level > current {
+		s.logger.Warnf("[logger] level set to %s", DefaultLevelNames.Name(level))
+		s.set(level)
+		return
+	}
+	s.set(level)
+	s.logger.Warnf("[logger] level set to %s", DefaultLevelNames.Name(level))
+}
+
+// set sets the levels and the default level of the routers, keeping their per-prefix levels.
+func (s *levelSignaler) set(level slog.Level) {
+	for _, lvl := range s.levels {
+		lvl.Set(level)
+	}
+	for _, r := range s.routers {
+		r.SetSpec(specWithDefault(r.Spec(), level)) // Always a valid spec.
+	}
+}
+
+// specWithDefault returns the level spec with its default level replaced by the given level.
+func specWithDefault(spec string, level slog.Level) string {
+	entries := []string{LevelSpecDefault + "=" + DefaultLevelNames.Name(level)}
+	for _, entry := range strings.Split(spec, ",") {
+		name, _, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == LevelSpecDefault {
+			continue // The default level, including an empty entry.
+		}
+		entries = append(entries, strings.TrimSpace(entry))
+	}
+	return strings.Join(entries, ",")
+}
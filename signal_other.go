@@ -0,0 +1,13 @@
+//go:build !unix
+
+package logger
+
+import (
+	"os"
+)
+
+// SIGUSR1/SIGUSR2 are not available.
+var (
+	debugSignal os.Signal
+	cycleSignal os.Signal
+)
@@ -0,0 +1,13 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	debugSignal os.Signal = syscall.SIGUSR1
+	cycleSignal os.Signal = syscall.SIGUSR2
+)
@@ -0,0 +1,118 @@
+//go:build unix
+
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHandleLevelSignals(t *testing.T) {
+	w := new(syncBuffer)
+	lvl := new(slog.LevelVar)
+	l := logger.WrapSlogHandler(logger.NewSlogTextHandler(w, &logger.SlogTextOption{Level: lvl, DisableTimestamp: true}))
+
+	stop := logger.HandleLevelSignals(l, &logger.LevelSignalOption{Timeout: 100 * time.Millisecond})
+	defer stop()
+
+	signal := func(sig syscall.Signal, expected slog.Level) {
+		t.Helper()
+
+		if err := syscall.Kill(os.Getpid(), sig); err != nil {
+			t.Fatal(err)
+		}
+		eventually(t, func() bool { return lvl.Level() == expected })
+	}
+
+	signal(syscall.SIGUSR1, slog.LevelDebug)
+	eventually(t, func() bool { return lvl.Level() == slog.LevelInfo }) // Timeout
+	if !strings.Contains(w.String(), "debug mode enabled for 100ms") || !strings.Contains(w.String(), "restoring levels") {
+		t.Errorf("got: %s", w)
+	}
+
+	signal(syscall.SIGUSR2, slog.LevelWarn)
+	signal(syscall.SIGUSR2, slog.LevelError)
+	signal(syscall.SIGUSR2, slog.LevelDebug)
+	signal(syscall.SIGUSR2, slog.LevelInfo)
+	if !strings.Contains(w.String(), "level set to ERROR") {
+		t.Errorf("got: %s", w)
+	}
+}
+
+func TestHandleLevelSignalsRouter(t *testing.T) {
+	w := new(syncBuffer)
+	r, err := logger.NewSlogLevelRouter(
+		logger.NewSlogTextHandler(w, &logger.SlogTextOption{Level: slog.LevelDebug, DisableTimestamp: true}),
+		&logger.SlogLevelRouterOption{Spec: "warn,[db]=info"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := logger.HandleLevelSignals(logger.WrapSlogHandler(r), &logger.LevelSignalOption{Timeout: time.Minute})
+
+	if err = syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return r.Spec() == "DEBUG" })
+
+	stop() // Reverts the debug mode.
+	if r.Spec() != "warn,[db]=info" {
+		t.Errorf("got: %s", r.Spec())
+	}
+
+	// The cycle changes the default level only, including when it cancels the debug mode.
+	stop = logger.HandleLevelSignals(logger.WrapSlogHandler(r), &logger.LevelSignalOption{Timeout: time.Minute})
+	defer stop()
+
+	if err = syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return r.Spec() == "default=ERROR,[db]=info" })
+
+	if err = syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return r.Spec() == "DEBUG" })
+	if err = syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return r.Spec() == "default=INFO,[db]=info" })
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
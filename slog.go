@@ -4,11 +4,18 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"runtime"
+	"sort"
 	"time"
 )
 
 type slogwrapper struct {
 	handler slog.Handler
+
+	// Set for V loggers, the records below slog.LevelWarn are logged at this level,
+	// the V(n) verbosity being checked by V and then the handler's level by log.
+	verbose bool
+	vlevel  slog.Level
 }
 
 // WrapSlog returns Logger based on log/slog backend.
@@ -46,9 +53,7 @@ func UnwrapSlogHandler(l Logger) (slog.Handler, bool) {
 }
 
 func (w *slogwrapper) WithPrefix(prefix string) Logger {
-	return &slogwrapper{
-		handler: w.handler.WithAttrs([]slog.Attr{slog.Any(KeyPrefix, prefix)}),
-	}
+	return w.with(w.handler.WithAttrs([]slog.Attr{slog.Any(KeyPrefix, prefix)}))
 }
 
 func (w *slogwrapper) WithPrefixf(format string, args ...any) Logger {
@@ -56,25 +61,41 @@ func (w *slogwrapper) WithPrefixf(format string, args ...any) Logger {
 }
 
 func (w *slogwrapper) WithField(key string, value any) Logger {
-	return &slogwrapper{
-		handler: w.handler.WithAttrs([]slog.Attr{slog.Any(key, value)}),
-	}
+	return w.with(w.handler.WithAttrs([]slog.Attr{slog.Any(key, value)}))
 }
 
 func (w *slogwrapper) WithError(err error) Logger {
-	return &slogwrapper{
-		handler: w.handler.WithAttrs([]slog.Attr{slog.Any("error", err)}),
-	}
+	return w.with(w.handler.WithAttrs([]slog.Attr{slog.Any("error", err)}))
 }
 
 func (w *slogwrapper) WithFields(fields map[string]any) Logger {
+	// Keys are sorted to get a stable order, regardless of the map iteration.
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	attrs := make([]slog.Attr, 0, len(fields))
-	for k, v := range fields {
-		attrs = append(attrs, slog.Any(k, v))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+
+	return w.with(w.handler.WithAttrs(attrs))
+}
+
+// V returns a logger logging at LevelV(level) the records below slog.LevelWarn.
+// The records must be enabled by both the verbosity (SetVerbosity/SetVModule) and the handler,
+// whose level must be lower or equal than LevelV(level) (e.g. with a *slog.LevelVar or a SlogLevelRouter rule).
+func (w *slogwrapper) V(level int) Logger {
+	if !verbose(level, 1) {
+		return NewNullLogger()
 	}
 
 	return &slogwrapper{
-		handler: w.handler.WithAttrs(attrs),
+		handler: w.handler,
+		verbose: true,
+		vlevel:  LevelV(level),
 	}
 }
 
@@ -111,44 +132,44 @@ func (w *slogwrapper) Errorf(format string, args ...any) {
 }
 
 func (w *slogwrapper) Print(args ...any) {
-	w.log(slog.LevelInfo, fmt.Sprint(args...))
+	w.logs(slog.LevelInfo, args)
 }
 
 func (w *slogwrapper) Printf(format string, args ...any) {
-	w.Infof(format, args...)
+	w.logf(slog.LevelInfo, format, args)
 }
 
 func (w *slogwrapper) Println(args ...any) {
-	w.Info(args...)
+	w.logln(slog.LevelInfo, args)
 }
 
 func (w *slogwrapper) Fatal(args ...any) {
-	w.log(slog.LevelError, fmt.Sprint(args...))
+	w.logs(slog.LevelError, args)
 	os.Exit(1)
 }
 
 func (w *slogwrapper) Fatalf(format string, args ...any) {
-	w.Errorf(format, args...)
+	w.logf(slog.LevelError, format, args)
 	os.Exit(1)
 }
 
 func (w *slogwrapper) Fatalln(args ...any) {
-	w.Error(args...)
+	w.logln(slog.LevelError, args)
 	os.Exit(1)
 }
 
 func (w *slogwrapper) Panic(args ...any) {
-	w.log(slog.LevelError, fmt.Sprint(args...))
+	w.logs(slog.LevelError, args)
 	panic(w)
 }
 
 func (w *slogwrapper) Panicf(format string, args ...any) {
-	w.Errorf(format, args...)
+	w.logf(slog.LevelError, format, args)
 	panic(w)
 }
 
 func (w *slogwrapper) Panicln(args ...any) {
-	w.Error(args...)
+	w.logln(slog.LevelError, args)
 	panic(w)
 }
 
@@ -157,6 +178,20 @@ func (w *slogwrapper) Panicln(args ...any) {
 //
 //
 
+func (w *slogwrapper) with(h slog.Handler) *slogwrapper {
+	return &slogwrapper{
+		handler: h,
+		verbose: w.verbose,
+		vlevel:  w.vlevel,
+	}
+}
+
+// All the logging methods call one of logs/logln/logf so the caller is always at the same depth.
+
+func (w *slogwrapper) logs(level slog.Level, args []any) {
+	w.log(level, fmt.Sprint(args...))
+}
+
 // join args with spaces. The \n at the end of string is trimed.
 func (w *slogwrapper) logln(level slog.Level, args []any) {
 	msg := fmt.Sprintln(args...)
@@ -168,11 +203,15 @@ func (w *slogwrapper) logf(level slog.Level, msg string, args []any) {
 }
 
 func (w *slogwrapper) log(level slog.Level, msg string) {
+	if w.verbose && level < slog.LevelWarn {
+		level = w.vlevel
+	}
 	if !w.handler.Enabled(void, level) {
 		return
 	}
 
-	var pc uintptr
-	r := slog.NewRecord(time.Now(), level, msg, pc)
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:]) // Skip [runtime.Callers, log, logs/logln/logf, logging method].
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
 	w.handler.Handle(void, r)
 }
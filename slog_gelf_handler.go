@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/mdouchement/logger/syslog"
 )
@@ -13,19 +16,89 @@ import (
 type (
 	// A SlogGELFOption holds SlogGELFHandler's options.
 	SlogGELFOption struct {
-		Level    slog.Level
+		// Set the logger's level. It is checked on every record so a *slog.LevelVar
+		// can be used to change the level at runtime. The default value is slog.LevelInfo.
+		Level    slog.Leveler
 		Hostname string
+
+		// Clock is the time source used for the records' timestamp.
+		// If not defined, the records' time is used.
+		Clock func() time.Time
+
+		// LevelNames are the names of the levels used for the `_level_name' field.
+		// The default value is DefaultLevelNames.
+		LevelNames LevelNames
+
+		// Severity returns the syslog severity of a level used for the `level' field.
+		// The default value is SyslogSeverity.
+		Severity func(slog.Level) syslog.Priority
+
+		// ReplaceAttr is called to rewrite each non-group attribute before it is logged,
+		// with the same semantics as slog.HandlerOptions.ReplaceAttr.
+		// It is called for the built-in attributes (slog.TimeKey, slog.LevelKey, slog.MessageKey and slog.SourceKey)
+		// with nil groups, for the handler's attributes and for the record's attributes.
+		// An attribute whose key is empty after replacement is discarded.
+		// The built-in attributes are written in their GELF fields when their values keep their kind
+		// (e.g. a time for slog.TimeKey), otherwise they are written as additional fields.
+		// The message can't be discarded because it is mandatory.
+		ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+		// AddSource adds the source code position of the log statement as `_file' and `_line' fields.
+		AddSource bool
+
+		// DuplicateKeys is the policy applied to the keys added several times,
+		// e.g. by a child handler or a record. The default value is DuplicateKeysLastWins.
+		DuplicateKeys DuplicateKeys
+
+		// FieldClashes is the strategy applied to the fields clashing with the reserved keys
+		// (`host', `level_name', `file'/`line' when AddSource is set, `truncated' when Limits are set
+		// and the names reserved by Graylog like `id', `source' or `gl2_*'). The default value is FieldClashesPrefix.
+		FieldClashes FieldClashes
+
+		// ClashPrefix is the prefix of the fields renamed by FieldClashesPrefix.
+		// The default value is DefaultClashPrefix.
+		ClashPrefix string
+
+		// Limits holds the size limits of the payloads, the truncated fields being listed in a `_truncated' field.
+		Limits GELFLimits
+
+		// Encoding holds the encoding options of the attribute values (e.g. native booleans or flattened maps).
+		Encoding GELFEncoding
+
+		// InvalidFieldNames is the policy applied to the fields whose names don't match `^[\w\.\-]*$'
+		// (e.g. with spaces or unicode). The default value is InvalidFieldNamesReplace.
+		InvalidFieldNames InvalidFieldNames
+
+		// OnInvalidFieldName is called with the errors of InvalidFieldNamesReport.
+		OnInvalidFieldName func(err error)
+
+		// Locker serializes the writes of the handler and its clones (WithAttrs/WithGroup) to the writer.
+		// A mutex shared by the clones is used by default, NopLocker can be used when the writer is already
+		// safe for concurrent use and a shared Locker when several handlers use the same writer.
+		Locker sync.Locker
+
+		// Deterministic produces a reproducible output, useful for golden files.
+		// The hostname defaults to DeterministicHostname instead of the machine's one,
+		// the time is fixed to DeterministicTime (unless a Clock is defined)
+		// and the durations are normalized to zero.
+		Deterministic bool
 	}
 
 	// A SlogGELFHandler is GELF formatter for log/slog.
 	SlogGELFHandler struct {
-		opt    *SlogGELFOption
-		writer io.Writer
+		opt     *SlogGELFOption
+		writer  io.Writer
+		clashes *clashResolver
 
-		parent *SlogGELFHandler
-		prefix string
-		group  string
-		attrs  []slog.Attr
+		prefix  string
+		groups  []string
+		gprefix string
+		// Rendered attrs of WithAttrs, written as is in each record.
+		fragment  []byte
+		spans     []gelfField
+		truncated []string
+		// Field clash of WithAttrs, returned by Handle.
+		err error
 	}
 )
 
@@ -34,7 +107,27 @@ func NewSlogGELFHandler(w io.Writer, o *SlogGELFOption) *SlogGELFHandler {
 	var err error
 
 	if o == nil {
-		o = &SlogGELFOption{Level: slog.LevelInfo}
+		o = &SlogGELFOption{}
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+	if o.Deterministic {
+		if o.Hostname == "" {
+			o.Hostname = DeterministicHostname
+		}
+		if o.Clock == nil {
+			o.Clock = deterministicClock
+		}
+	}
+	if o.LevelNames == nil {
+		o.LevelNames = DefaultLevelNames
+	}
+	if o.Severity == nil {
+		o.Severity = SyslogSeverity
+	}
+	if o.Locker == nil {
+		o.Locker = new(sync.Mutex)
 	}
 	if o.Hostname == "" {
 		o.Hostname, err = os.Hostname()
@@ -43,34 +136,64 @@ func NewSlogGELFHandler(w io.Writer, o *SlogGELFOption) *SlogGELFHandler {
 		}
 	}
 
+	reserved := []string{"host", "level_name"}
+	if o.AddSource {
+		reserved = append(reserved, "file", "line")
+	}
+	if o.Limits != (GELFLimits{}) {
+		reserved = append(reserved, truncatedKey)
+	}
+	clashes := newClashResolver(o.FieldClashes, o.ClashPrefix, reserved...)
+	clashes.isReserved = isGELFReserved
+
 	return &SlogGELFHandler{
-		opt:    o,
-		writer: w,
+		opt:     o,
+		writer:  w,
+		clashes: clashes,
 	}
 }
 
 // Enabled reports whether the handler handles records at the given level.
 func (h *SlogGELFHandler) Enabled(_ context.Context, l slog.Level) bool {
-	return l >= h.opt.Level
+	return l >= h.opt.Level.Level()
 }
 
 // WithAttrs returns a new Handler whose attributes consist of
 // both the receiver's attributes and the arguments.
+// The attributes are rendered once here instead of on every record.
 func (h *SlogGELFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
 
 	nh := h.Clone() // Since h is cloned, it's just read, never edited so it's safe.
-	nh.attrs = make([]slog.Attr, 0, len(attrs))
 	for _, attr := range attrs {
 		if attr.Key == KeyPrefix {
 			nh.prefix += attr.Value.String()
-			continue
 		}
+	}
 
-		nh.attrs = append(nh.attrs, attr)
+	// The fragment is copied because the duplicated keys can be edited in place.
+	gelf := &BufferGELF{
+		buf:       bytes.NewBuffer(slices.Clone(h.fragment)),
+		fields:    slices.Clone(h.spans),
+		truncated: slices.Clone(h.truncated),
+	}
+	gelf.SetDuplicateKeys(h.opt.DuplicateKeys)
+	gelf.SetInvalidFieldNames(h.opt.InvalidFieldNames, h.opt.OnInvalidFieldName)
+	gelf.SetEncoding(h.opt.Encoding)
+	gelf.SetLimits(h.opt.Limits)
+	fields, err := h.walker().fields(nh.gprefix, nh.groups, attrs)
+	if err != nil {
+		nh.err = err
+		return nh
+	}
+	for _, f := range fields {
+		gelf.addField(f.key, f.value)
 	}
+	nh.fragment = gelf.Bytes()
+	nh.spans = gelf.fields
+	nh.truncated = gelf.truncated
 
 	return nh
 }
@@ -83,102 +206,108 @@ func (h *SlogGELFHandler) WithGroup(name string) slog.Handler {
 	}
 
 	nh := h.Clone() // Since h is cloned, it's just read, never edited so it's safe.
-	nh.group = name
+	nh.groups = append(nh.groups[:len(nh.groups):len(nh.groups)], name)
+	nh.gprefix += name + delimiter
 	return nh
 }
 
 // Handle handles the Record.
 func (h *SlogGELFHandler) Handle(_ context.Context, record slog.Record) error {
-	gelf := NewBufferGELF()
-
-	// Get all parents in a list.
-	ilineage := make([]*SlogGELFHandler, 0, 100)
-	p := h
-	for p != nil {
-		ilineage = append(ilineage, p)
-		p = p.parent
+	if h.err != nil {
+		return h.err
 	}
 
-	// Process handler's groups/attrs.
-	var gprefix string
-	for i := len(ilineage) - 1; i >= 0; i-- {
-		p = ilineage[i]
-		if p.group != "" {
-			gprefix += p.group + delimiter
-		}
+	gelf := NewBufferGELF()
+	defer gelf.Release()
 
-		for _, attr := range ilineage[i].attrs {
-			gelf.Add(gprefix+attr.Key, attr.Value.Any())
-		}
-	}
+	gelf.SetDuplicateKeys(h.opt.DuplicateKeys)
+	gelf.SetInvalidFieldNames(h.opt.InvalidFieldNames, h.opt.OnInvalidFieldName)
+	gelf.SetEncoding(h.opt.Encoding)
+	gelf.SetLimits(h.opt.Limits)
+	gelf.fragment(h.fragment, h.spans, h.truncated)
 
 	// Process record's groups/attrs.
+	walker := h.walker()
 	if record.NumAttrs() > 0 {
+		var err error
 		record.Attrs(func(attr slog.Attr) bool {
-			gelfrecord(gelf, gprefix, attr)
-			return true
+			err = walker.walk(h.gprefix, h.groups, attr, gelf)
+			return err == nil
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	// Main fields.
-	gelf.Host(h.opt.Hostname)
-	gelf.Timestamp(record.Time)
-	gelf.Level(h.priorities(record.Level))
-
-	if h.prefix != "" {
-		record.Message = fmt.Sprintf("%s %s", h.prefix, record.Message)
+	if h.opt.AddSource {
+		if src := recordSource(record); src != nil {
+			attr := walker.builtin(slog.Any(slog.SourceKey, src))
+			if src, ok := attr.Value.Any().(*slog.Source); ok {
+				gelf.builtin("file", src.File)
+				gelf.builtin("line", src.Line)
+			} else if attr.Key != "" {
+				gelf.Add(attr.Key, attr.Value.Any())
+			}
+		}
 	}
-	gelf.Message(record.Message)
 
-	gelf.Add("level_name", record.Level.String())
-	// m["_file"]
-	// m["_line"]
+	if h.opt.Clock != nil {
+		record.Time = h.opt.Clock()
+	}
 
-	_, err := h.writer.Write(gelf.Complete(true))
-	return err
-}
+	// Main fields.
+	gelf.Host(h.opt.Hostname)
 
-// Clone clones the entry, it creates a new instance and linking the parent to it.
-func (h *SlogGELFHandler) Clone() *SlogGELFHandler {
-	nh := &SlogGELFHandler{
-		parent: h,
-		opt:    h.opt,
-		prefix: h.prefix,
-		writer: h.writer,
+	attr := walker.builtin(slog.Time(slog.TimeKey, record.Time))
+	if attr.Value.Kind() == slog.KindTime {
+		gelf.Timestamp(attr.Value.Time())
+	} else if attr.Key != "" {
+		gelf.Add(attr.Key, attr.Value.Any())
 	}
 
-	return nh
-}
+	// The built-in level and message attrs are only built for the replace function to avoid allocations.
+	level, isLevel := record.Level, true
+	var levelAttr slog.Attr
+	if walker.replace != nil {
+		levelAttr = walker.builtin(slog.Any(slog.LevelKey, record.Level))
+		level, isLevel = levelAttr.Value.Any().(slog.Level)
+	}
+	if isLevel {
+		gelf.Level(int32(h.opt.Severity(level)))
+	}
 
-func (SlogGELFHandler) priorities(level slog.Level) int32 {
-	var p syslog.Priority
+	if walker.replace != nil {
+		if h.prefix != "" {
+			record.Message = h.prefix + " " + record.Message
+		}
+		gelf.Message(walker.builtin(slog.String(slog.MessageKey, record.Message)).Value.String())
+	} else {
+		gelf.message(h.prefix, record.Message)
+	}
 
-	switch level {
-	case slog.LevelError:
-		p = syslog.LOG_ERR
-	case slog.LevelWarn:
-		p = syslog.LOG_WARNING
-	case slog.LevelInfo:
-		p = syslog.LOG_INFO
-	case slog.LevelDebug:
-		p = syslog.LOG_DEBUG
+	if isLevel {
+		gelf.addValue("level_name", func() { gelf.string(h.opt.LevelNames.Name(level), true) }, 0)
+	} else if levelAttr.Key != "" {
+		gelf.Add(levelAttr.Key, levelAttr.Value.Any())
 	}
 
-	return int32(p)
+	h.opt.Locker.Lock()
+	defer h.opt.Locker.Unlock()
+
+	_, err := h.writer.Write(gelf.Complete(true))
+	return err
 }
 
-func gelfrecord(gelf *BufferGELF, p string, attr slog.Attr) {
-	if attr.Value.Kind() != slog.KindGroup {
-		gelf.Add(p+attr.Key, attr.Value.Any())
-		return
-	}
+// Clone clones the handler, the clone shares the receiver's rendered attrs which are never edited.
+func (h *SlogGELFHandler) Clone() *SlogGELFHandler {
+	nh := *h
+	return &nh
+}
 
-	p += attr.Key + delimiter
-	for _, a := range attr.Value.Group() {
-		if a.Value.Kind() == slog.KindGroup {
-			gelfrecord(gelf, p, a)
-			continue
-		}
-		gelf.Add(p+a.Key, a.Value.Any())
+func (h *SlogGELFHandler) walker() attrWalker {
+	return attrWalker{
+		replace:       h.opt.ReplaceAttr,
+		clashes:       h.clashes,
+		deterministic: h.opt.Deterministic,
 	}
 }
@@ -80,3 +80,54 @@ func TestSlogRecord(t *testing.T) {
 		t.Errorf("got: %s", line)
 	}
 }
+
+func TestSlogGELFPrerenderedAttrs(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname-42", Deterministic: true}))
+
+	l = l.With(logger.KeyPrefix, "[uuid]", "root", 1)
+	l1 := l.With("child", 1)
+	l2 := l.With("child", 2) // Must not overwrite the fragment of l1.
+
+	l1.Info("first line\nsecond line", "rec", 1.5)
+	l2.Info("info")
+
+	expected := `{"version":"1.1","_root":1,"_child":1,"_rec":1.5,"host":"hostname-42","timestamp":946684800,"level":6,"short_message":"[uuid] first line","full_message":"[uuid] first line\nsecond line","_level_name":"INFO"}` + "\n" +
+		`{"version":"1.1","_root":1,"_child":2,"host":"hostname-42","timestamp":946684800,"level":6,"short_message":"[uuid] info","_level_name":"INFO"}` + "\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %s\nexpect: %s", w, expected)
+	}
+}
+
+func TestSlogGELFDuplicateKeys(t *testing.T) {
+	for _, tc := range []struct {
+		policy   logger.DuplicateKeys
+		expected string
+	}{
+		{policy: logger.DuplicateKeysLastWins, expected: `{"version":"1.1","_a":1,"_k":"record","_level_name":"INFO"}`},
+		{policy: logger.DuplicateKeysFirstWins, expected: `{"version":"1.1","_k":"parent","_a":1,"_level_name":"INFO"}`},
+		{policy: logger.DuplicateKeysSuffix, expected: `{"version":"1.1","_k":"parent","_a":1,"_k_2":"child","_k_3":"record","_level_name":"INFO"}`},
+		{policy: logger.DuplicateKeysArray, expected: `{"version":"1.1","_k":["parent","child","record"],"_a":1,"_level_name":"INFO"}`},
+	} {
+		w := new(bytes.Buffer)
+		l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{
+			DuplicateKeys: tc.policy,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				switch a.Key {
+				case slog.TimeKey, slog.MessageKey:
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+
+		parent := l.With("k", "parent", "a", 1)
+		parent.With("k", "sibling") // Must not edit the parent's fields.
+		parent.With("k", "child").Info("", "k", "record")
+
+		got := regexp.MustCompile(`,"(host|level|short_message)":("[^"]*"|\d+)`).ReplaceAllString(w.String(), "")
+		if got != tc.expected+"\n" {
+			t.Errorf("policy %d\n   got: %s\nexpect: %s", tc.policy, got, tc.expected)
+		}
+	}
+}
@@ -3,21 +3,139 @@ package logger
 import (
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+
+	"github.com/mdouchement/logger/syslog"
+)
+
+// Additional levels following the syslog severities.
+// LevelTrace is the same level as LevelV(4), so the V(4) records are displayed as TRACE by default.
+const (
+	LevelTrace     = slog.LevelDebug - 4
+	LevelNotice    = slog.LevelInfo + 2
+	LevelCritical  = slog.LevelError + 4
+	LevelAlert     = slog.LevelError + 8
+	LevelEmergency = slog.LevelError + 12
 )
 
+// LevelNames maps levels to their display names.
+// A level without name is displayed relatively to the closest lower named level (e.g. `INFO+1`),
+// except below slog.LevelDebug where it is displayed as a verbosity level (e.g. `V2`).
+type LevelNames map[slog.Level]string
+
+// DefaultLevelNames holds the level names used by default by the handlers and ParseSlogLevel.
+var DefaultLevelNames = LevelNames{
+	LevelTrace:      "TRACE",
+	slog.LevelDebug: "DEBUG",
+	slog.LevelInfo:  "INFO",
+	LevelNotice:     "NOTICE",
+	slog.LevelWarn:  "WARN",
+	slog.LevelError: "ERROR",
+	LevelCritical:   "CRITICAL",
+	LevelAlert:      "ALERT",
+	LevelEmergency:  "EMERGENCY",
+}
+
+var levelAliases = map[string]string{
+	"warning": "warn",
+	"err":     "error",
+	"crit":    "critical",
+	"emerg":   "emergency",
+}
+
 // ParseSlogLevel takes a string level and returns the slog.Level constant.
+// It reads the DefaultLevelNames (see LevelNames.Parse).
 func ParseSlogLevel(lvl string) (slog.Level, error) {
-	switch strings.ToLower(lvl) {
-	case "debug":
-		return slog.LevelDebug, nil
-	case "info":
-		return slog.LevelInfo, nil
-	case "warn", "warning":
-		return slog.LevelWarn, nil
-	case "error":
-		return slog.LevelError, nil
+	return DefaultLevelNames.Parse(lvl)
+}
+
+// Name returns the display name of the level.
+func (n LevelNames) Name(l slog.Level) string {
+	if name, ok := n[l]; ok {
+		return name
+	}
+	if l < slog.LevelDebug {
+		return "V" + strconv.Itoa(int(slog.LevelDebug-l))
+	}
+
+	found := false
+	var closest slog.Level
+	for level := range n {
+		if level < l && (!found || level > closest) {
+			closest = level
+			found = true
+		}
+	}
+	if !found {
+		return l.String()
+	}
+
+	return fmt.Sprintf("%s+%d", n[closest], l-closest)
+}
+
+// Parse takes a case-insensitive string level and returns the slog.Level.
+// It accepts the names (plus the aliases warning, err, crit and emerg), an offset from a name
+// (e.g. `info+2` or `error-1`), a verbosity (e.g. `v2`) and a numeric level (e.g. `-4`).
+func (n LevelNames) Parse(lvl string) (slog.Level, error) {
+	s := strings.ToLower(strings.TrimSpace(lvl))
+
+	if v, err := strconv.Atoi(s); err == nil {
+		return slog.Level(v), nil
+	}
+
+	if len(s) > 1 && s[0] == 'v' {
+		if v, err := strconv.Atoi(s[1:]); err == nil && v >= 0 {
+			return LevelV(v), nil
+		}
+	}
+
+	name, offset := s, 0
+	if i := strings.LastIndexAny(s, "+-"); i > 0 {
+		if o, err := strconv.Atoi(s[i:]); err == nil {
+			name, offset = s[:i], o
+		}
+	}
+	if alias, ok := levelAliases[name]; ok {
+		name = alias
+	}
+
+	for level, n := range n {
+		if strings.ToLower(n) == name {
+			return level + slog.Level(offset), nil
+		}
 	}
 
 	return slog.LevelError, fmt.Errorf("not a valid slog level: %s", lvl)
 }
+
+// SyslogSeverity returns the syslog severity of the level according to the following ranges:
+//
+//	             level < INFO      => LOG_DEBUG
+//	INFO      <= level < NOTICE    => LOG_INFO
+//	NOTICE    <= level < WARN      => LOG_NOTICE
+//	WARN      <= level < ERROR     => LOG_WARNING
+//	ERROR     <= level < CRITICAL  => LOG_ERR
+//	CRITICAL  <= level < ALERT     => LOG_CRIT
+//	ALERT     <= level < EMERGENCY => LOG_ALERT
+//	EMERGENCY <= level             => LOG_EMERG
+func SyslogSeverity(l slog.Level) syslog.Priority {
+	switch {
+	case l < slog.LevelInfo:
+		return syslog.LOG_DEBUG
+	case l < LevelNotice:
+		return syslog.LOG_INFO
+	case l < slog.LevelWarn:
+		return syslog.LOG_NOTICE
+	case l < slog.LevelError:
+		return syslog.LOG_WARNING
+	case l < LevelCritical:
+		return syslog.LOG_ERR
+	case l < LevelAlert:
+		return syslog.LOG_CRIT
+	case l < LevelEmergency:
+		return syslog.LOG_ALERT
+	default:
+		return syslog.LOG_EMERG
+	}
+}
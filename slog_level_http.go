@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type levelHTTPHandler struct {
+	levels map[string]*slog.LevelVar
+}
+
+// NewLevelHTTPHandler returns an http.Handler that shows and sets the given named levels as JSON.
+//
+//	GET returns the current levels: {"http":"INFO","db":"DEBUG"}
+//	PUT/POST sets the given levels: {"db":"warn"}
+//
+// The levels are parsed with ParseSlogLevel and the update is atomic:
+// no level is changed if one of them is unknown or invalid.
+func NewLevelHTTPHandler(levels map[string]*slog.LevelVar) http.Handler {
+	h := &levelHTTPHandler{
+		levels: make(map[string]*slog.LevelVar, len(levels)),
+	}
+	for name, lvl := range levels {
+		h.levels[name] = lvl
+	}
+
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *levelHTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut, http.MethodPost:
+		if err := h.set(r); err != nil {
+			h.error(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		h.error(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed: %s", r.Method))
+		return
+	}
+
+	levels := make(map[string]string, len(h.levels))
+	for name, lvl := range h.levels {
+		levels[name] = DefaultLevelNames.Name(lvl.Level())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levels)
+}
+
+func (h *levelHTTPHandler) set(r *http.Request) error {
+	var payload map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	levels := make(map[*slog.LevelVar]slog.Level, len(payload))
+	for name, value := range payload {
+		lvl, ok := h.levels[name]
+		if !ok {
+			return fmt.Errorf("unknown level name: %s", name)
+		}
+
+		l, err := ParseSlogLevel(value)
+		if err != nil {
+			return err
+		}
+		levels[lvl] = l
+	}
+
+	for lvl, l := range levels {
+		lvl.Set(l)
+	}
+	return nil
+}
+
+func (levelHTTPHandler) error(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,83 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestSlogLevelVar(t *testing.T) {
+	lvl := new(slog.LevelVar)
+	text := logger.NewSlogTextHandler(new(bytes.Buffer), &logger.SlogTextOption{Level: lvl})
+	gelf := logger.NewSlogGELFHandler(new(bytes.Buffer), &logger.SlogGELFOption{Level: lvl})
+
+	for _, h := range []slog.Handler{text, gelf, text.WithAttrs([]slog.Attr{slog.Int("k", 1)})} {
+		lvl.Set(slog.LevelInfo)
+		if h.Enabled(context.Background(), slog.LevelDebug) {
+			t.Errorf("%T: debug must be disabled", h)
+		}
+
+		lvl.Set(slog.LevelDebug)
+		if !h.Enabled(context.Background(), slog.LevelDebug) {
+			t.Errorf("%T: debug must be enabled", h)
+		}
+	}
+}
+
+func TestLevelHTTPHandler(t *testing.T) {
+	web := new(slog.LevelVar)
+	db := new(slog.LevelVar)
+	db.Set(slog.LevelWarn)
+	h := logger.NewLevelHTTPHandler(map[string]*slog.LevelVar{"http": web, "db": db})
+
+	//
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != "{\"db\":\"WARN\",\"http\":\"INFO\"}\n" {
+		t.Errorf("got: %d %s", w.Code, w.Body)
+	}
+
+	//
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(`{"db":"debug"}`)))
+	if w.Code != 200 || w.Body.String() != "{\"db\":\"DEBUG\",\"http\":\"INFO\"}\n" {
+		t.Errorf("got: %d %s", w.Code, w.Body)
+	}
+	if db.Level() != slog.LevelDebug {
+		t.Errorf("got: %s", db.Level())
+	}
+
+	//
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(`{"http":"error","db":"nope"}`)))
+	if w.Code != 400 {
+		t.Errorf("got: %d %s", w.Code, w.Body)
+	}
+	if web.Level() != slog.LevelInfo {
+		t.Errorf("level must not be changed on error, got: %s", web.Level())
+	}
+
+	//
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(`{"cache":"error"}`)))
+	if w.Code != 400 {
+		t.Errorf("got: %d %s", w.Code, w.Body)
+	}
+
+	//
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/", nil))
+	if w.Code != 405 {
+		t.Errorf("got: %d %s", w.Code, w.Body)
+	}
+}
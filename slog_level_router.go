@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// LevelSpecDefault is the name used in a level spec for the default level.
+const LevelSpecDefault = "default"
+
+type (
+	// A SlogLevelRouterOption holds SlogLevelRouter's options.
+	SlogLevelRouterOption struct {
+		// Spec defines the levels per prefix/component,
+		// e.g. `default=info,[db]=debug,[http]=warn`.
+		// The default level is slog.LevelInfo when not defined in the spec.
+		Spec string
+
+		// ComponentKey is the key of the attr whose value is matched against the spec
+		// like prefixes are, e.g. `component`. If not defined, only prefixes are matched.
+		ComponentKey string
+	}
+
+	// A SlogLevelRouter is a slog.Handler that chooses the level of the records
+	// according to the prefixes/components of the logger.
+	// It can be used in front of any slog.Handler, the records being enabled by both the spec and
+	// the wrapped handler: the levels of the spec can't get below the wrapped handler's level,
+	// which is usually the lowest level of the spec (e.g. DEBUG).
+	SlogLevelRouter struct {
+		handler slog.Handler
+		routes  *levelRoutes
+
+		// Matching names from the root to the leaf.
+		names []string
+		chain string
+	}
+
+	levelRoutes struct {
+		componentKey string
+		spec         atomic.Pointer[levelSpec]
+	}
+
+	levelSpec struct {
+		raw          string
+		defaultLevel slog.Level
+		levels       map[string]slog.Level
+	}
+)
+
+// NewSlogLevelRouter returns a new SlogLevelRouter that wraps h.
+func NewSlogLevelRouter(h slog.Handler, o *SlogLevelRouterOption) (*SlogLevelRouter, error) {
+	if o == nil {
+		o = &SlogLevelRouterOption{}
+	}
+
+	r := &SlogLevelRouter{
+		handler: h,
+		routes: &levelRoutes{
+			componentKey: o.ComponentKey,
+		},
+	}
+
+	if err := r.SetSpec(o.Spec); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetSpec replaces the spec at runtime.
+// It is applied to all the handlers derived from the router.
+func (r *SlogLevelRouter) SetSpec(spec string) error {
+	s, err := parseLevelSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	r.routes.spec.Store(s)
+	return nil
+}
+
+// Spec returns the current spec.
+func (r *SlogLevelRouter) Spec() string {
+	return r.routes.spec.Load().raw
+}
+
+// Enabled reports whether the handler handles records at the given level.
+// The level of the innermost matching prefix/component is used, the prefix chain
+// accumulated up to a prefix being checked before the prefix itself, then the wrapped handler's level.
+func (r *SlogLevelRouter) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= r.level() && r.handler.Enabled(ctx, l)
+}
+
+// WithAttrs returns a new Handler whose attributes consist of
+// both the receiver's attributes and the arguments.
+func (r *SlogLevelRouter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return r
+	}
+
+	nr := r.clone(r.handler.WithAttrs(attrs))
+	for _, attr := range attrs {
+		switch {
+		case attr.Key == KeyPrefix:
+			prefix := attr.Value.String()
+			nr.chain += prefix
+			nr.names = append(nr.names, prefix, nr.chain) // The chain has precedence over the prefix alone.
+		case r.routes.componentKey != "" && attr.Key == r.routes.componentKey:
+			nr.names = append(nr.names, attr.Value.String())
+		}
+	}
+
+	return nr
+}
+
+// WithGroup returns a new Handler with the given group appended to
+// the receiver's existing groups.
+func (r *SlogLevelRouter) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+
+	return r.clone(r.handler.WithGroup(name))
+}
+
+// Handle handles the Record.
+func (r *SlogLevelRouter) Handle(ctx context.Context, record slog.Record) error {
+	return r.handler.Handle(ctx, record)
+}
+
+func (r *SlogLevelRouter) clone(h slog.Handler) *SlogLevelRouter {
+	return &SlogLevelRouter{
+		handler: h,
+		routes:  r.routes,
+		names:   r.names[:len(r.names):len(r.names)], // Force copy on append.
+		chain:   r.chain,
+	}
+}
+
+func (r *SlogLevelRouter) level() slog.Level {
+	spec := r.routes.spec.Load()
+	if len(spec.levels) == 0 {
+		return spec.defaultLevel
+	}
+
+	for i := len(r.names) - 1; i >= 0; i-- {
+		if l, ok := spec.levels[r.names[i]]; ok {
+			return l
+		}
+	}
+
+	return spec.defaultLevel
+}
+
+// parseLevelSpec parses spec like `default=info,[db]=debug,[http]=warn`.
+// A level without name defines the default level.
+func parseLevelSpec(spec string) (*levelSpec, error) {
+	s := &levelSpec{
+		raw:          spec,
+		defaultLevel: slog.LevelInfo,
+		levels:       map[string]slog.Level{},
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			name, value = LevelSpecDefault, name
+		}
+		name = strings.TrimSpace(name)
+
+		l, err := ParseSlogLevel(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level spec %q: %w", entry, err)
+		}
+
+		if name == LevelSpecDefault {
+			s.defaultLevel = l
+			continue
+		}
+		s.levels[name] = l
+	}
+
+	return s, nil
+}
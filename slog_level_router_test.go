@@ -0,0 +1,111 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestSlogLevelRouter(t *testing.T) {
+	w := new(bytes.Buffer)
+	h, err := logger.NewSlogLevelRouter(
+		logger.NewSlogTextHandler(w, &logger.SlogTextOption{Level: slog.LevelDebug - 10, DisableTimestamp: true}),
+		&logger.SlogLevelRouterOption{
+			Spec:         "default=info, [db]=debug,[http]=warn,[db][slow]=error,cache=error",
+			ComponentKey: "component",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := logger.WrapSlogHandler(h)
+	l.Debug("root debug")
+	l.Info("root info")
+	l.WithPrefix("[db]").Debug("db debug")
+	l.WithPrefix("[db]").WithPrefix("[slow]").Warn("db slow warn")
+	l.WithPrefix("[db]").WithPrefix("[query]").Debug("db query debug")
+	l.WithPrefix("[http]").Info("http info")
+	l.WithPrefix("[http]").Warn("http warn")
+	l.WithPrefix("[http]").WithPrefix("[db]").Debug("http db debug")
+	l.WithField("component", "cache").Warn("cache warn")
+	l.WithPrefix("[db]").WithField("component", "cache").Warn("db cache warn")
+
+	expected := strings.Join([]string{
+		`level=INFO msg="root info"`,
+		`level=DEBUG msg="[db] db debug"`,
+		`level=DEBUG msg="[db][query] db query debug"`,
+		`level=WARN msg="[http] http warn"`,
+		`level=DEBUG msg="[http][db] http db debug"`,
+		"",
+	}, "\n")
+	if w.String() != expected {
+		t.Errorf("\n   got:\n%s\nexpect:\n%s", w, expected)
+	}
+
+	//
+
+	dbl := l.WithPrefix("[db]")
+	if err = h.SetSpec("warn"); err != nil {
+		t.Fatal(err)
+	}
+	if h.Spec() != "warn" {
+		t.Errorf("got: %s", h.Spec())
+	}
+
+	w.Reset()
+	dbl.Info("db info")
+	l.Warn("root warn")
+	if w.String() != "level=WARN msg=\"root warn\"\n" {
+		t.Errorf("got: %s", w)
+	}
+
+	//
+
+	if err = h.SetSpec("default=info,[db]=nope"); err == nil {
+		t.Error("an error is expected")
+	}
+	if h.Spec() != "warn" {
+		t.Errorf("spec must not be changed on error, got: %s", h.Spec())
+	}
+
+	//
+
+	// The prefix is matched through a group.
+	if err = h.SetSpec("info,[http]=debug"); err != nil {
+		t.Fatal(err)
+	}
+	sh := slog.New(h).WithGroup("g").With(logger.KeyPrefix, "[http]").Handler()
+	if !sh.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug must be enabled by the [http] rule")
+	}
+	if slog.New(h).WithGroup("g").Handler().Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug must be disabled without prefix")
+	}
+}
+
+func TestSlogLevelRouterWrappedLevel(t *testing.T) {
+	h, err := logger.NewSlogLevelRouter(
+		logger.NewSlogTextHandler(new(bytes.Buffer), &logger.SlogTextOption{Level: slog.LevelInfo}),
+		&logger.SlogLevelRouterOption{Spec: "warn,[db]=debug"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The levels of the spec can't get below the wrapped handler's level.
+	db := slog.New(h).With(logger.KeyPrefix, "[db]").Handler()
+	if db.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("debug must be disabled by the wrapped handler")
+	}
+	if !db.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("info must be enabled by the [db] rule")
+	}
+	if h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Error("info must be disabled by the default rule")
+	}
+}
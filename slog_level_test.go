@@ -0,0 +1,166 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/logger/syslog"
+)
+
+func TestParseSlogLevel(t *testing.T) {
+	for lvl, expected := range map[string]slog.Level{
+		"trace":     logger.LevelTrace,
+		"TRACE+1":   logger.LevelTrace + 1,
+		"debug":     slog.LevelDebug,
+		"INFO":      slog.LevelInfo,
+		"warn":      slog.LevelWarn,
+		"warning":   slog.LevelWarn,
+		"error":     slog.LevelError,
+		"notice":    logger.LevelNotice,
+		"Critical":  logger.LevelCritical,
+		"crit":      logger.LevelCritical,
+		"alert":     logger.LevelAlert,
+		"emergency": logger.LevelEmergency,
+		"info+2":    slog.LevelInfo + 2,
+		"ERROR-1":   slog.LevelError - 1,
+		"v2":        logger.LevelV(2),
+		"-6":        slog.Level(-6),
+		"12":        slog.Level(12),
+	} {
+		l, err := logger.ParseSlogLevel(lvl)
+		if err != nil {
+			t.Errorf("%s: %s", lvl, err)
+		}
+		if l != expected {
+			t.Errorf("%s: got %s, expect %s", lvl, l, expected)
+		}
+	}
+
+	for _, lvl := range []string{"", "info+", "nope+2", "v"} {
+		if _, err := logger.ParseSlogLevel(lvl); err == nil {
+			t.Errorf("%s: an error is expected", lvl)
+		}
+	}
+
+	names := logger.LevelNames{logger.LevelTrace: "TRACE", slog.LevelInfo: "INFO", slog.LevelError: "fatal-error"}
+	if l, err := names.Parse("trace"); err != nil || l != logger.LevelTrace {
+		t.Errorf("got: %s %v", l, err)
+	}
+	if l, err := names.Parse("fatal-error+1"); err != nil || l != slog.LevelError+1 {
+		t.Errorf("got: %s %v", l, err)
+	}
+}
+
+func TestLevelNames(t *testing.T) {
+	for l, expected := range map[slog.Level]string{
+		slog.LevelDebug:           "DEBUG",
+		slog.LevelInfo:            "INFO",
+		slog.LevelInfo + 1:        "INFO+1",
+		logger.LevelNotice:        "NOTICE",
+		slog.LevelWarn + 1:        "WARN+1",
+		logger.LevelCritical:      "CRITICAL",
+		logger.LevelEmergency:     "EMERGENCY",
+		logger.LevelEmergency + 5: "EMERGENCY+5",
+		logger.LevelV(3):          "V3",
+		logger.LevelTrace:         "TRACE",
+		logger.LevelV(5):          "V5",
+	} {
+		if name := logger.DefaultLevelNames.Name(l); name != expected {
+			t.Errorf("%d: got %s, expect %s", l, name, expected)
+		}
+	}
+
+	names := logger.LevelNames{logger.LevelTrace: "TRACE", slog.LevelInfo: "INFO"}
+	for l, expected := range map[slog.Level]string{
+		logger.LevelTrace: "TRACE",
+		logger.LevelV(1):  "V1",
+		slog.LevelDebug:   "TRACE+4",
+		slog.LevelError:   "INFO+8",
+	} {
+		if name := names.Name(l); name != expected {
+			t.Errorf("%d: got %s, expect %s", l, name, expected)
+		}
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	for l, expected := range map[slog.Level]syslog.Priority{
+		logger.LevelV(4):          syslog.LOG_DEBUG,
+		slog.LevelDebug:           syslog.LOG_DEBUG,
+		slog.LevelInfo:            syslog.LOG_INFO,
+		slog.LevelInfo + 1:        syslog.LOG_INFO,
+		slog.LevelInfo + 2:        syslog.LOG_NOTICE,
+		slog.LevelWarn:            syslog.LOG_WARNING,
+		slog.LevelError:           syslog.LOG_ERR,
+		slog.LevelError + 2:       syslog.LOG_ERR,
+		logger.LevelCritical:      syslog.LOG_CRIT,
+		logger.LevelAlert:         syslog.LOG_ALERT,
+		logger.LevelEmergency + 1: syslog.LOG_EMERG,
+	} {
+		if p := logger.SyslogSeverity(l); p != expected {
+			t.Errorf("%d: got %d, expect %d", l, p, expected)
+		}
+	}
+}
+
+func TestSlogCustomLevels(t *testing.T) {
+	w := new(bytes.Buffer)
+	l := slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname-42"}))
+	l.Log(context.Background(), slog.LevelInfo+2, "notice")
+
+	expected := regexp.MustCompile(`"level":5,"short_message":"notice","_level_name":"NOTICE"\}`)
+	if !expected.MatchString(w.String()) {
+		t.Errorf("got: %s", w)
+	}
+
+	//
+
+	w.Reset()
+	l = slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{
+		Level:            logger.LevelTrace,
+		ForceFormatting:  true,
+		ForceColors:      true,
+		DisableTimestamp: true,
+		LevelNames:       logger.LevelNames{logger.LevelTrace: "TRACE", slog.LevelInfo: "INFO", logger.LevelCritical: "CRITICAL"},
+		LevelStyles:      map[slog.Level]string{logger.LevelTrace: "magenta", logger.LevelCritical: "red+b"},
+	}))
+	l.Log(context.Background(), logger.LevelTrace, "trace")
+	l.Log(context.Background(), logger.LevelTrace+1, "v3")
+	l.Log(context.Background(), slog.LevelDebug, "debug")
+	l.Log(context.Background(), slog.LevelInfo+2, "notice")
+	l.Log(context.Background(), logger.LevelCritical+1, "critical")
+
+	expected2 := strings.Join([]string{
+		"\x1b[0;35mTRACE\x1b[0m trace",
+		"\x1b[0;35m   V3\x1b[0m v3",
+		"\x1b[0;34mTRACE+4\x1b[0m debug",
+		"\x1b[0;32mINFO+2\x1b[0m notice",
+		"\x1b[0;1;31mCRITICAL+1\x1b[0m critical",
+		"",
+	}, "\n")
+	if w.String() != expected2 {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected2)
+	}
+}
+
+func TestSlogTraceLevel(t *testing.T) {
+	w := new(bytes.Buffer)
+	slog.New(logger.NewSlogTextHandler(w, &logger.SlogTextOption{Level: logger.LevelTrace, DisableTimestamp: true})).
+		Log(context.Background(), logger.LevelTrace, "trace")
+	if w.String() != "level=TRACE msg=trace\n" {
+		t.Errorf("got: %s", w)
+	}
+
+	w.Reset()
+	slog.New(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname-42", Level: logger.LevelTrace})).
+		Log(context.Background(), logger.LevelTrace, "trace")
+	expected := regexp.MustCompile(`"level":7,"short_message":"trace","_level_name":"TRACE"\}`)
+	if !expected.MatchString(w.String()) {
+		t.Errorf("got: %s", w)
+	}
+}
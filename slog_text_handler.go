@@ -1,21 +1,27 @@
 package logger
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"regexp"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mgutz/ansi"
 )
 
 type (
 	// SlogTextOption holds SlogTextHandler's options.
 	SlogTextOption struct {
-		// Set the logger's level.
-		Level slog.Level
+		// Set the logger's level. It is checked on every record so a *slog.LevelVar
+		// can be used to change the level at runtime. The default value is slog.LevelInfo.
+		Level slog.Leveler
 
 		// Set to true to bypass checking for a TTY before outputting colors.
 		ForceColors bool
@@ -26,6 +32,15 @@ type (
 		// Force formatted layout, even for non-TTY output.
 		ForceFormatting bool
 
+		// Force sanitizing, even for non-TTY output.
+		// The sanitizing neutralizes the control characters, escape sequences and bidi overrides
+		// of the messages, keys and values so they can't be interpreted by the terminal.
+		// It is enabled by default for a TTY.
+		ForceSanitizing bool
+
+		// Force disabling sanitizing.
+		DisableSanitizing bool
+
 		// Disable timestamp logging. useful when output is redirected to logging
 		// system that already adds timestamps.
 		DisableTimestamp bool
@@ -42,9 +57,18 @@ type (
 
 		// The fields are sorted by default for a consistent output. For applications
 		// that log extremely frequently and don't use the JSON formatter this may not
-		// be desired.
+		// be desired. When disabled, the fields are written in their insertion order:
+		// the handler's attrs from the parents to the children, then the record's attrs.
 		DisableSorting bool
 
+		// PriorityKeys are the keys of the fields written first, in the given order (e.g. `request_id', `user').
+		// The other fields are ordered according to KeyComparator and DisableSorting.
+		PriorityKeys []string
+
+		// KeyComparator orders the fields by their keys, like strings.Compare.
+		// It is used even if DisableSorting is set. The default order is alphabetical.
+		KeyComparator func(a, b string) int
+
 		// Wrap empty fields in quotes if true.
 		QuoteEmptyFields bool
 
@@ -52,6 +76,11 @@ type (
 		// with something else. For example: ', or `.
 		QuoteCharacter string
 
+		// Use strict logfmt for the non-formatted layout: the keys and values are escaped
+		// and quoted when needed so a record is parseable and fits on one line.
+		// QuoteCharacter is ignored in this mode.
+		StrictLogfmt bool
+
 		// Pad msg field with spaces on the right for display.
 		// The value for this parameter will be the size of padding.
 		// Its default value is zero, which means no padding will be applied for msg.
@@ -65,6 +94,65 @@ type (
 		// ValueFormatter is the format of the value when logs are pretty printed.
 		// The default value is `%v'. You can use `%+v' to print the stacktrace of github.com/pkg/errors.
 		ValueFormatter string
+
+		// Clock is the time source used for the records' time and the time passed since
+		// the first record of the handler and its clones. Building the handler doesn't call it.
+		// If not defined, the records' time and the time passed since the beginning of execution are used.
+		Clock func() time.Time
+
+		// LevelNames are the display names of the levels.
+		// The default value is DefaultLevelNames.
+		LevelNames LevelNames
+
+		// LevelStyles are the ansi styles (e.g. `magenta+b`) of the levels, applied to the level range starting
+		// at the given level. A level without style uses the color scheme according to its range
+		// (e.g. a level between INFO and WARN is colored as INFO).
+		LevelStyles map[slog.Level]string
+
+		// ReplaceAttr is called to rewrite each non-group attribute before it is logged,
+		// with the same semantics as slog.HandlerOptions.ReplaceAttr.
+		// It is called for the built-in attributes (slog.TimeKey, slog.LevelKey, slog.MessageKey and slog.SourceKey)
+		// with nil groups, for the handler's attributes and for the record's attributes.
+		// An attribute whose key is empty after replacement is discarded.
+		ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+		// AddSource adds the source code position of the log statement as `source=file:line`.
+		AddSource bool
+
+		// FieldClashes is the strategy applied to the fields clashing with the reserved keys
+		// (`time', `level', `msg' and `source' when AddSource is set). The default value is FieldClashesPrefix.
+		FieldClashes FieldClashes
+
+		// ClashPrefix is the prefix of the fields renamed by FieldClashesPrefix.
+		// The default value is DefaultClashPrefix.
+		ClashPrefix string
+
+		// Locker serializes the writes of the handler and its clones (WithAttrs/WithGroup) to the writer.
+		// A mutex shared by the clones is used by default, NopLocker can be used when the writer is already
+		// safe for concurrent use and a shared Locker when several handlers use the same writer.
+		Locker sync.Locker
+
+		// Deterministic produces a reproducible output, useful for golden files.
+		// The time is fixed to DeterministicTime (unless a Clock is defined),
+		// the fields are always sorted and the durations are normalized to zero.
+		Deterministic bool
+	}
+
+	levelColor struct {
+		level slog.Level
+		code  string
+	}
+
+	// clockStart is the time of the first record of a handler using a Clock.
+	clockStart struct {
+		once sync.Once
+		t    time.Time
+	}
+
+	// A textField is a field whose value is already rendered.
+	textField struct {
+		key   string
+		value []byte
 	}
 
 	// A SlogTextHandler is Logrus text formatter for log/slog.
@@ -72,57 +160,169 @@ type (
 		opt    SlogTextOption
 		writer io.Writer
 
-		template string
 		// Whether the logger's out is to a terminal.
 		isTerminal bool
-		// Color scheme to use.
-		colorScheme *compiledColorScheme
+		formatted  bool
+		sanitizing bool
+		// Comparison function of the keys, nil for the insertion order.
+		order func(a, b string) int
+		// The default alphabetical order, the fields are merged without being compared by order.
+		alphabetical bool
+		// Color codes to use.
+		colors *colorCodes
+		// Compiled LevelStyles in ascending order.
+		levelColors []levelColor
+		clashes     *clashResolver
+		// Time of the first record, shared by the clones, used with a Clock to compute the time passed.
+		start *clockStart
+
+		prefix  string
+		groups  []string
+		gprefix string
+		// Rendered attrs of WithAttrs, without duplicated keys.
+		fields []textField
+		// Field clash of WithAttrs, returned by Handle.
+		err error
+	}
 
-		parent *SlogTextHandler
-		prefix string
-		group  string
-		attrs  []slog.Attr
+	// textBuiltins holds the built-in attributes after replacement.
+	textBuiltins struct {
+		time       slog.Attr
+		levelKey   string
+		level      slog.Level
+		isLevel    bool
+		levelValue slog.Value
+		msgKey     string
+		prefix     string // Written before the message, separated by a space.
+		msg        string
+	}
+
+	// textState holds the buffers used to handle a record.
+	textState struct {
+		h      *SlogTextHandler
+		level  slog.Level // The record's level, used to color the keys.
+		buf    []byte
+		values []byte
+		fields []textField
+		over   [][]byte
+		order  []textField
 	}
 )
 
+// The pooled buffers bigger than that are dropped so a huge record doesn't retain memory.
+const maxPooledTextBuffer = 64 << 10
+
+var textStatePool = sync.Pool{
+	New: func() any {
+		return &textState{
+			buf:    make([]byte, 0, 1024),
+			values: make([]byte, 0, 1024),
+		}
+	},
+}
+
 // NewSlogTextHandler returns a new SlogTextHandler.
 func NewSlogTextHandler(w io.Writer, o *SlogTextOption) *SlogTextHandler {
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
 	if len(o.QuoteCharacter) == 0 {
 		o.QuoteCharacter = "\""
 	}
 	if len(o.ValueFormatter) == 0 {
 		o.ValueFormatter = "%v"
 	}
+	if len(o.TimestampFormat) == 0 {
+		o.TimestampFormat = defaultTimestampFormat
+	}
+	if o.Deterministic && o.Clock == nil {
+		o.Clock = deterministicClock
+	}
+	if o.LevelNames == nil {
+		o.LevelNames = DefaultLevelNames
+	}
+	if o.Locker == nil {
+		o.Locker = new(sync.Mutex)
+	}
+
+	levelColors := make([]levelColor, 0, len(o.LevelStyles))
+	for l, style := range o.LevelStyles {
+		levelColors = append(levelColors, levelColor{level: l, code: ansi.ColorCode(style)})
+	}
+	slices.SortFunc(levelColors, func(a, b levelColor) int { return int(a.level - b.level) })
+
+	sorted := !o.DisableSorting || o.Deterministic
+
+	reserved := []string{slog.TimeKey, slog.LevelKey, slog.MessageKey}
+	if o.AddSource {
+		reserved = append(reserved, slog.SourceKey)
+	}
+
+	isTerminal := checkIfTerminal(w)
+	colors := noColorCodes
+	if (o.ForceColors || isTerminal) && !o.DisableColors {
+		colors = defaultColorCodes
+	}
 
 	return &SlogTextHandler{
-		writer:     w,
-		opt:        *o,
-		template:   " %s=" + o.ValueFormatter,
-		isTerminal: checkIfTerminal(w),
+		writer:       w,
+		opt:          *o,
+		isTerminal:   isTerminal,
+		formatted:    o.ForceFormatting || isTerminal,
+		sanitizing:   (o.ForceSanitizing || isTerminal) && !o.DisableSanitizing,
+		order:        keyOrder(sorted, o.PriorityKeys, o.KeyComparator),
+		alphabetical: sorted && o.KeyComparator == nil && len(o.PriorityKeys) == 0,
+		colors:       colors,
+		start:        new(clockStart),
+		levelColors:  levelColors,
+		clashes:      newClashResolver(o.FieldClashes, o.ClashPrefix, reserved...),
 	}
 }
 
 // Enabled reports whether the handler handles records at the given level.
 func (h *SlogTextHandler) Enabled(_ context.Context, l slog.Level) bool {
-	return l >= h.opt.Level
+	return l >= h.opt.Level.Level()
 }
 
 // WithAttrs returns a new Handler whose attributes consist of
 // both the receiver's attributes and the arguments.
+// The attributes are rendered once here instead of on every record.
 func (h *SlogTextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
 		return h
 	}
 
 	nh := h.Clone()
-	nh.attrs = make([]slog.Attr, 0, len(attrs))
 	for _, attr := range attrs {
 		if attr.Key == KeyPrefix {
 			nh.prefix += attr.Value.String()
-			continue
 		}
+	}
 
-		nh.attrs = append(nh.attrs, attr)
+	fields, err := h.walker().fields(nh.gprefix, nh.groups, attrs)
+	if err != nil {
+		nh.err = err
+		return nh
+	}
+	if len(fields) == 0 {
+		return nh
+	}
+
+	nh.fields = make([]textField, len(h.fields), len(h.fields)+len(fields))
+	copy(nh.fields, h.fields)
+next:
+	for _, f := range fields {
+		value := h.appendValue(nil, f.value)
+		for i := range nh.fields {
+			if nh.fields[i].key == f.key {
+				nh.fields[i].value = value // The last value wins, the field keeps its position.
+				continue next
+			}
+		}
+		nh.fields = append(nh.fields, textField{key: f.key, value: value})
+	}
+	if nh.order != nil {
+		slices.SortStableFunc(nh.fields, func(a, b textField) int { return nh.order(a.key, b.key) })
 	}
 
 	return nh
@@ -136,209 +336,549 @@ func (h *SlogTextHandler) WithGroup(name string) slog.Handler {
 	}
 
 	nh := h.Clone()
-	nh.group = name
+	nh.groups = append(nh.groups[:len(nh.groups):len(nh.groups)], name)
+	nh.gprefix += name + delimiter
 	return nh
 }
 
 // Handle handles the Record.
 func (h *SlogTextHandler) Handle(_ context.Context, record slog.Record) error {
-	if h.prefix != "" {
-		record.Message = fmt.Sprintf("%s %s", h.prefix, record.Message)
+	if h.err != nil {
+		return h.err
 	}
 
-	gprefix, keys, m := h.build()
+	s := newTextState(h)
+	defer s.free()
+
+	if h.opt.Clock != nil {
+		record.Time = h.opt.Clock()
+	}
+
+	walker := h.walker()
+	if h.opt.AddSource {
+		if src := recordSource(record); src != nil {
+			attr := walker.builtin(slog.Any(slog.SourceKey, src))
+			if attr.Key != "" {
+				s.addField(attr.Key, sourceValue(attr.Value))
+			}
+		}
+	}
 
 	if record.NumAttrs() > 0 {
+		var err error
 		record.Attrs(func(attr slog.Attr) bool {
-			keys = append(keys, grouprecord(m, gprefix, attr)...)
-			return true
+			err = walker.walk(h.gprefix, h.groups, attr, s)
+			return err == nil
 		})
+		if err != nil {
+			return err
+		}
 	}
 
-	lastKeyIdx := len(keys) - 1
-
-	if !h.opt.DisableSorting {
-		sort.Strings(keys)
+	builtins := h.builtins(walker, record)
+	s.level = builtins.level
+	if h.formatted {
+		s.buf = h.appendFormatted(s.buf, record, builtins)
+	} else {
+		s.buf = h.appendPlain(s.buf, builtins)
 	}
+	s.buf = s.appendFields(s.buf)
+
+	s.buf = append(s.buf, '\n')
+
+	h.opt.Locker.Lock()
+	defer h.opt.Locker.Unlock()
+
+	_, err := h.writer.Write(s.buf)
+	return err
+}
+
+// Clone clones the handler, the clone shares the receiver's rendered attrs which are never edited.
+func (h *SlogTextHandler) Clone() *SlogTextHandler {
+	nh := *h
+	return &nh
+}
 
-	b := new(bytes.Buffer)
-	prefixFieldClashes(m)
+func (h *SlogTextHandler) walker() attrWalker {
+	return attrWalker{
+		replace:       h.opt.ReplaceAttr,
+		clashes:       h.clashes,
+		deterministic: h.opt.Deterministic,
+	}
+}
 
-	timestampFormat := h.opt.TimestampFormat
-	if timestampFormat == "" {
-		timestampFormat = defaultTimestampFormat
+// fieldIndex returns the index of the handler's field with the given key or -1.
+func (h *SlogTextHandler) fieldIndex(key string) int {
+	if h.alphabetical {
+		i, ok := slices.BinarySearchFunc(h.fields, key, func(f textField, key string) int { return strings.Compare(f.key, key) })
+		if !ok {
+			return -1
+		}
+		return i
 	}
 
-	if h.opt.ForceFormatting || h.isTerminal { // Is formatted
-		isColored := (h.opt.ForceColors || h.isTerminal) && !h.opt.DisableColors
-		var colorScheme *compiledColorScheme
-		if isColored {
-			colorScheme = h.colorScheme
-			if colorScheme == nil {
-				colorScheme = defaultCompiledColorScheme
-			}
-		} else {
-			colorScheme = noColorsColorScheme
+	for i := range h.fields {
+		if h.fields[i].key == key {
+			return i
 		}
+	}
+	return -1
+}
 
-		h.printColored(b, record, keys, m, timestampFormat, colorScheme)
-	} else {
-		h.appendKeyValue(b, "level", record.Level.String(), true)
+// builtins returns the built-in attributes of the record.
+// The replace function is only called when defined so no attr is allocated otherwise.
+func (h *SlogTextHandler) builtins(walker attrWalker, record slog.Record) textBuiltins {
+	b := textBuiltins{
+		time:     slog.Time(slog.TimeKey, record.Time),
+		levelKey: slog.LevelKey,
+		level:    record.Level,
+		isLevel:  true,
+		msgKey:   slog.MessageKey,
+		prefix:   h.prefix,
+		msg:      record.Message,
+	}
+	if walker.replace == nil {
+		return b
+	}
+
+	b.time = walker.builtin(b.time)
+
+	level := walker.builtin(slog.Any(slog.LevelKey, record.Level))
+	b.levelKey = level.Key
+	b.level, b.isLevel = level.Value.Any().(slog.Level)
+	if !b.isLevel {
+		b.level = record.Level
+		b.levelValue = level.Value
+	}
+
+	if b.prefix != "" {
+		b.msg = b.prefix + " " + b.msg
+		b.prefix = ""
+	}
+	msg := walker.builtin(slog.String(slog.MessageKey, b.msg))
+	b.msgKey = msg.Key
+	b.msg = msg.Value.String()
 
-		if !h.opt.DisableTimestamp {
-			h.appendKeyValue(b, "time", record.Time.Format(timestampFormat), true)
+	return b
+}
+
+// appendPlain appends the built-in attrs as key=value pairs.
+func (h *SlogTextHandler) appendPlain(b []byte, builtins textBuiltins) []byte {
+	sep := false
+	separate := func(b []byte) []byte {
+		if sep {
+			return append(b, ' ')
 		}
+		sep = true
+		return b
+	}
 
-		if record.Message != "" {
-			h.appendKeyValue(b, "msg", record.Message, lastKeyIdx >= 0)
+	if builtins.levelKey != "" {
+		b = separate(b)
+		b = h.appendKey(b, builtins.levelKey)
+		b = append(b, '=')
+		if builtins.isLevel {
+			b = h.appendString(b, h.opt.LevelNames.Name(builtins.level))
+		} else {
+			b = h.appendString(b, builtins.levelValue.String())
 		}
+	}
 
-		for i, key := range keys {
-			h.appendKeyValue(b, key, m[key], lastKeyIdx != i)
+	if !h.opt.DisableTimestamp && builtins.time.Key != "" {
+		b = separate(b)
+		b = h.appendKey(b, builtins.time.Key)
+		b = append(b, '=')
+		b = h.appendTime(b, builtins.time.Value)
+	}
+
+	if builtins.msgKey != "" && (builtins.prefix != "" || builtins.msg != "") {
+		b = separate(b)
+		b = h.appendKey(b, builtins.msgKey)
+		b = append(b, '=')
+		if builtins.prefix == "" {
+			b = h.appendString(b, builtins.msg)
+		} else {
+			b = h.appendPrefixedMessage(b, builtins)
 		}
 	}
 
-	b.WriteByte('\n')
-	_, err := h.writer.Write(b.Bytes())
-	return err
+	return b
 }
 
-// Clone clones the entry, it creates a new instance and linking the parent to it.
-func (h *SlogTextHandler) Clone() *SlogTextHandler {
-	return &SlogTextHandler{
-		parent:      h,
-		template:    h.template,
-		isTerminal:  h.isTerminal,
-		colorScheme: h.colorScheme,
-		prefix:      h.prefix,
-		opt:         h.opt,
-		writer:      h.writer,
-	}
-}
-
-func (h *SlogTextHandler) build() (string, []string, map[string]any) {
-	// Get all parents in a list.
-	ilineage := make([]*SlogTextHandler, 0, 100)
-	p := h
-	for p != nil {
-		ilineage = append(ilineage, p)
-		p = p.parent
-	}
-
-	// Process attrs from parents to children.
-	var gprefix string
-	keys := make([]string, 0, 100)
-	m := make(map[string]any)
-	for i := len(ilineage) - 1; i >= 0; i-- {
-		p = ilineage[i]
-		if p.group != "" {
-			gprefix += p.group + delimiter
+// appendFormatted appends the built-in attrs in the formatted layout: `[time] LEVEL message'.
+func (h *SlogTextHandler) appendFormatted(b []byte, record slog.Record, builtins textBuiltins) []byte {
+	if !h.opt.DisableTimestamp && builtins.time.Key != "" {
+		b = appendColorStart(b, h.colors.Timestamp)
+		b = append(b, '[')
+		if !h.opt.FullTimestamp {
+			t := record.Time
+			if builtins.time.Value.Kind() == slog.KindTime {
+				t = builtins.time.Value.Time()
+			}
+			b = appendZeroPadded(b, h.miniTS(t), 4)
+		} else if builtins.time.Value.Kind() == slog.KindTime {
+			b = builtins.time.Value.Time().AppendFormat(b, h.opt.TimestampFormat)
+		} else {
+			b = append(b, builtins.time.Value.String()...)
 		}
+		b = append(b, ']')
+		b = appendColorEnd(b, h.colors.Timestamp)
+		b = append(b, ' ')
+	}
 
-		for _, attr := range ilineage[i].attrs {
-			k := gprefix + attr.Key
-			if _, ok := m[k]; !ok {
-				keys = append(keys, k)
-			}
+	if builtins.levelKey != "" {
+		text := "WARN"
+		switch {
+		case !builtins.isLevel:
+			text = builtins.levelValue.String()
+		case builtins.level != slog.LevelWarn:
+			text = h.opt.LevelNames.Name(builtins.level)
+		case h.opt.DisableUppercase:
+			text = "warn"
+		}
+		if !h.opt.DisableUppercase {
+			text = strings.ToUpper(text)
+		}
 
-			m[k] = attr.Value.Any()
+		code := h.levelColor(builtins.level)
+		b = appendColorStart(b, code)
+		for n := utf8.RuneCountInString(text); n < 5; n++ {
+			b = append(b, ' ')
 		}
+		b = append(b, text...)
+		b = appendColorEnd(b, code)
+		b = append(b, ' ')
+	}
+
+	start := len(b)
+	if builtins.prefix != "" {
+		b = h.appendText(b, builtins.prefix)
+		b = append(b, ' ')
 	}
+	b = h.appendText(b, builtins.msg)
 
-	return gprefix, keys, m
+	if h.opt.PrefixRE != nil && h.colors.Prefix != "" {
+		if loc := h.opt.PrefixRE.FindIndex(b[start:]); loc != nil {
+			b = insertAt(b, start+loc[1]-loc[0], ansi.Reset)
+			b = insertAt(b, start, h.colors.Prefix)
+		}
+	}
+
+	for n := utf8.RuneCount(b[start:]); n < h.opt.SpacePadding; n++ {
+		b = append(b, ' ')
+	}
+
+	return b
 }
 
-func (h *SlogTextHandler) appendKeyValue(b *bytes.Buffer, key string, value any, appendSpace bool) {
-	b.WriteString(key)
-	b.WriteByte('=')
-	h.appendValue(b, value)
+// appendKey appends a field's key according to the layout, sanitized when needed.
+func (h *SlogTextHandler) appendKey(b []byte, key string) []byte {
+	if h.sanitizing {
+		key = sanitize(key)
+	}
+	if h.opt.StrictLogfmt && !h.formatted {
+		return appendLogfmtKey(b, key)
+	}
+	return append(b, key...)
+}
 
-	if appendSpace {
-		b.WriteByte(' ')
+// appendText appends a text of the formatted layout, sanitized when needed.
+func (h *SlogTextHandler) appendText(b []byte, s string) []byte {
+	if h.sanitizing {
+		return appendSanitized(b, s)
 	}
+	return append(b, s...)
 }
 
-func (h *SlogTextHandler) printColored(b *bytes.Buffer, record slog.Record, keys []string, m map[string]any, timestampFormat string, colorScheme *compiledColorScheme) {
-	var levelColor func(string) string
-	var levelText string
-	switch record.Level {
-	case slog.LevelInfo:
-		levelColor = colorScheme.InfoLevelColor
-	case slog.LevelWarn:
-		levelColor = colorScheme.WarnLevelColor
-	case slog.LevelError:
-		levelColor = colorScheme.ErrorLevelColor
-	default:
-		levelColor = colorScheme.DebugLevelColor
+// appendString appends a string value according to the layout, quoting it when needed.
+func (h *SlogTextHandler) appendString(b []byte, s string) []byte {
+	switch {
+	case h.formatted:
+		return h.appendText(b, s)
+	case h.opt.StrictLogfmt:
+		return appendLogfmtValue(b, s, h.opt.QuoteEmptyFields)
 	}
 
-	levelText = "warn"
-	if record.Level != slog.LevelWarn {
-		levelText = record.Level.String()
+	if h.sanitizing {
+		s = sanitize(s)
+	}
+	if !textNeedsQuoting(s, h.opt.QuoteEmptyFields) {
+		return append(b, s...)
 	}
 
-	if !h.opt.DisableUppercase {
-		levelText = strings.ToUpper(levelText)
+	b = append(b, h.opt.QuoteCharacter...)
+	b = append(b, s...)
+	return append(b, h.opt.QuoteCharacter...)
+}
+
+// appendPrefixedMessage appends the message and its prefix as a quoted value of the plain layout.
+func (h *SlogTextHandler) appendPrefixedMessage(b []byte, builtins textBuiltins) []byte {
+	if h.opt.StrictLogfmt {
+		b = append(b, '"')
+		b = appendLogfmtEscaped(b, builtins.prefix)
+		b = append(b, ' ')
+		b = appendLogfmtEscaped(b, builtins.msg)
+		return append(b, '"')
 	}
 
-	level := levelColor(fmt.Sprintf("%5s", levelText))
+	b = append(b, h.opt.QuoteCharacter...)
+	b = h.appendText(b, builtins.prefix)
+	b = append(b, ' ')
+	b = h.appendText(b, builtins.msg)
+	return append(b, h.opt.QuoteCharacter...)
+}
+
+// appendTime appends the time value of the plain layout.
+func (h *SlogTextHandler) appendTime(b []byte, v slog.Value) []byte {
+	if v.Kind() != slog.KindTime {
+		return h.appendString(b, v.String())
+	}
+
+	start := len(b)
+	b = v.Time().AppendFormat(b, h.opt.TimestampFormat)
+	return h.quoteRendered(b, start)
+}
+
+// appendValue appends a field's value according to the layout.
+func (h *SlogTextHandler) appendValue(b []byte, v slog.Value) []byte {
+	if h.formatted && h.opt.ValueFormatter != "%v" {
+		if h.sanitizing {
+			return appendSanitized(b, fmt.Sprintf(h.opt.ValueFormatter, v.Any()))
+		}
+		return fmt.Appendf(b, h.opt.ValueFormatter, v.Any())
+	}
 
-	message := record.Message
-	if h.opt.PrefixRE != nil {
-		if h.opt.PrefixRE.MatchString(message) {
-			match := h.opt.PrefixRE.FindString(message)
-			message = colorScheme.PrefixColor(message[:len(match)]) + message[len(match):]
+	switch v.Kind() {
+	case slog.KindString:
+		return h.appendString(b, v.String())
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return h.appendString(b, err.Error())
+		}
+		if !h.sanitizing && (h.formatted || !h.opt.StrictLogfmt) {
+			return fmt.Append(b, v.Any())
 		}
+		return h.appendString(b, fmt.Sprint(v.Any()))
 	}
 
-	messageFormat := "%s"
-	if h.opt.SpacePadding != 0 {
-		messageFormat = fmt.Sprintf("%%-%ds", h.opt.SpacePadding)
+	// The other kinds never need to be sanitized.
+	start := len(b)
+	b = appendValueText(b, v)
+	if h.formatted || (!h.opt.StrictLogfmt && !h.sanitizing) {
+		return b
 	}
+	return h.quoteRendered(b, start)
+}
 
-	if h.opt.DisableTimestamp {
-		fmt.Fprintf(b, "%s "+messageFormat, level, message)
-	} else {
-		var timestamp string
-		if !h.opt.FullTimestamp {
-			timestamp = fmt.Sprintf("[%04d]", miniTS())
-		} else {
-			timestamp = fmt.Sprintf("[%s]", record.Time.Format(timestampFormat))
+// quoteRendered quotes the value rendered from start when needed by the plain layout.
+func (h *SlogTextHandler) quoteRendered(b []byte, start int) []byte {
+	if h.opt.StrictLogfmt {
+		if !logfmtNeedsQuoting(b[start:]) {
+			return b
 		}
-		fmt.Fprintf(b, "%s %s "+messageFormat, colorScheme.TimestampColor(timestamp), level, message)
+		return appendLogfmtValue(b[:start], string(b[start:]), false)
 	}
 
-	for _, k := range keys {
-		fmt.Fprintf(b, h.template, levelColor(k), m[k])
+	if !textNeedsQuoting(b[start:], false) {
+		return b
 	}
+	b = insertAt(b, start, h.opt.QuoteCharacter)
+	return append(b, h.opt.QuoteCharacter...)
 }
 
-func (h *SlogTextHandler) appendValue(b *bytes.Buffer, value any) {
-	switch value := value.(type) {
-	case string:
-		if !h.needsQuoting(value) {
-			b.WriteString(value)
-		} else {
-			fmt.Fprintf(b, "%s%v%s", h.opt.QuoteCharacter, value, h.opt.QuoteCharacter)
+// levelColor returns the color code of the level's range.
+// A level style applies until the next styled level or the next color scheme's range.
+func (h *SlogTextHandler) levelColor(l slog.Level) string {
+	var boundary slog.Level
+	var code string
+	switch {
+	case l < slog.LevelDebug:
+		boundary, code = l, h.colors.DebugLevel
+	case l < slog.LevelInfo:
+		boundary, code = slog.LevelDebug, h.colors.DebugLevel
+	case l < slog.LevelWarn:
+		boundary, code = slog.LevelInfo, h.colors.InfoLevel
+	case l < slog.LevelError:
+		boundary, code = slog.LevelWarn, h.colors.WarnLevel
+	case l < LevelCritical:
+		boundary, code = slog.LevelError, h.colors.ErrorLevel
+	case l < LevelAlert:
+		boundary, code = LevelCritical, h.colors.FatalLevel
+	default:
+		boundary, code = LevelAlert, h.colors.PanicLevel
+	}
+
+	if h.colors != noColorCodes {
+		for i := len(h.levelColors) - 1; i >= 0; i-- {
+			lc := h.levelColors[i]
+			if lc.level <= l {
+				if lc.level >= boundary || l < slog.LevelDebug {
+					return lc.code
+				}
+				break
+			}
 		}
-	case error:
-		errmsg := value.Error()
-		if !h.needsQuoting(errmsg) {
-			b.WriteString(errmsg)
-		} else {
-			fmt.Fprintf(b, "%s%v%s", h.opt.QuoteCharacter, errmsg, h.opt.QuoteCharacter)
+	}
+
+	return code
+}
+
+// miniTS returns the number of seconds passed since the beginning of execution.
+// When a Clock is defined, the given record time is relative to the handler's first record.
+func (h *SlogTextHandler) miniTS(t time.Time) int {
+	if h.opt.Clock == nil {
+		return miniTS()
+	}
+
+	h.start.once.Do(func() { h.start.t = t })
+	return int(t.Sub(h.start.t) / time.Second)
+}
+
+func newTextState(h *SlogTextHandler) *textState {
+	s := textStatePool.Get().(*textState)
+	s.h = h
+	if cap(s.over) < len(h.fields) {
+		s.over = make([][]byte, len(h.fields))
+	}
+	s.over = s.over[:len(h.fields)]
+	return s
+}
+
+func (s *textState) free() {
+	if cap(s.buf) > maxPooledTextBuffer || cap(s.values) > maxPooledTextBuffer {
+		return
+	}
+
+	clear(s.fields)
+	clear(s.over)
+	clear(s.order)
+	s.h = nil
+	s.buf = s.buf[:0]
+	s.values = s.values[:0]
+	s.fields = s.fields[:0]
+	textStatePool.Put(s)
+}
+
+// addField renders a record's field, the last value of a key wins.
+func (s *textState) addField(key string, value slog.Value) {
+	start := len(s.values)
+	s.values = s.h.appendValue(s.values, value)
+	rendered := s.values[start:len(s.values):len(s.values)]
+
+	if i := s.h.fieldIndex(key); i >= 0 {
+		s.over[i] = rendered
+		return
+	}
+	for i := range s.fields {
+		if s.fields[i].key == key {
+			s.fields[i].value = rendered
+			return
+		}
+	}
+	s.fields = append(s.fields, textField{key: key, value: rendered})
+}
+
+// appendFields appends the handler's fields followed by the record's ones,
+// or all of them ordered by key.
+func (s *textState) appendFields(b []byte) []byte {
+	h := s.h
+	switch {
+	case h.alphabetical:
+		slices.SortFunc(s.fields, compareTextFields)
+		i, j := 0, 0
+		for i < len(h.fields) || j < len(s.fields) {
+			if j == len(s.fields) || (i < len(h.fields) && h.fields[i].key < s.fields[j].key) {
+				b = s.appendField(b, h.fields[i].key, s.value(i))
+				i++
+				continue
+			}
+			b = s.appendField(b, s.fields[j].key, s.fields[j].value)
+			j++
+		}
+		return b
+	case h.order != nil && len(s.fields) > 0:
+		s.order = s.order[:0]
+		for i, f := range h.fields {
+			s.order = append(s.order, textField{key: f.key, value: s.value(i)})
+		}
+		s.order = append(s.order, s.fields...)
+		slices.SortStableFunc(s.order, func(a, b textField) int { return h.order(a.key, b.key) })
+		for _, f := range s.order {
+			b = s.appendField(b, f.key, f.value)
+		}
+		return b
+	}
+
+	for i, f := range h.fields {
+		b = s.appendField(b, f.key, s.value(i))
+	}
+	for _, f := range s.fields {
+		b = s.appendField(b, f.key, f.value)
+	}
+	return b
+}
+
+// value returns the value of the handler's field i, overridden by the record.
+func (s *textState) value(i int) []byte {
+	if s.over[i] != nil {
+		return s.over[i]
+	}
+	return s.h.fields[i].value
+}
+
+func (s *textState) appendField(b []byte, key string, value []byte) []byte {
+	h := s.h
+	if h.formatted || len(b) > 0 {
+		b = append(b, ' ')
+	}
+
+	if h.formatted {
+		if h.sanitizing {
+			key = sanitize(key)
 		}
+		b = appendColored(b, h.levelColor(s.level), key)
+	} else {
+		b = h.appendKey(b, key)
+	}
+
+	b = append(b, '=')
+	return append(b, value...)
+}
+
+func compareTextFields(a, b textField) int {
+	return strings.Compare(a.key, b.key)
+}
+
+// appendValueText appends the text of a value as formatted by fmt with `%v'.
+func appendValueText(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return append(b, v.String()...)
+	case slog.KindInt64:
+		return strconv.AppendInt(b, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(b, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(b, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(b, v.Bool())
+	case slog.KindDuration:
+		return append(b, v.Duration().String()...)
+	case slog.KindTime:
+		return v.Time().AppendFormat(b, "2006-01-02 15:04:05.999999999 -0700 MST")
 	default:
-		fmt.Fprint(b, value)
+		return fmt.Append(b, v.Any())
 	}
 }
 
-func (h *SlogTextHandler) needsQuoting(text string) bool {
-	if h.opt.QuoteEmptyFields && len(text) == 0 {
+// textNeedsQuoting reports whether the text must be quoted in the plain layout.
+func textNeedsQuoting[T string | []byte](text T, quoteEmpty bool) bool {
+	if quoteEmpty && len(text) == 0 {
 		return true
 	}
 
-	for _, ch := range text {
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
 		if !((ch >= 'a' && ch <= 'z') ||
 			(ch >= 'A' && ch <= 'Z') ||
 			(ch >= '0' && ch <= '9') ||
@@ -349,32 +889,38 @@ func (h *SlogTextHandler) needsQuoting(text string) bool {
 	return false
 }
 
-func grouprecord(m map[string]any, p string, attr slog.Attr) []string {
-	var keys []string
-
-	if attr.Value.Kind() != slog.KindGroup {
-		k := p + attr.Key
-		if _, ok := m[k]; !ok {
-			keys = append(keys, k)
-		}
+// appendZeroPadded appends n padded with zeros like `%0<width>d'.
+func appendZeroPadded(b []byte, n int, width int) []byte {
+	if n < 0 {
+		b = append(b, '-')
+		n = -n
+		width--
+	}
 
-		m[k] = attr.Value.Any()
-		return keys
+	var digits [20]byte
+	d := strconv.AppendInt(digits[:0], int64(n), 10)
+	for i := len(d); i < width; i++ {
+		b = append(b, '0')
 	}
+	return append(b, d...)
+}
 
-	p += attr.Key + delimiter
-	for _, a := range attr.Value.Group() {
-		if a.Value.Kind() == slog.KindGroup {
-			keys = append(keys, grouprecord(m, p, a)...)
-			continue
-		}
+// insertAt inserts s at the index i of b.
+func insertAt(b []byte, i int, s string) []byte {
+	n := len(b)
+	b = append(b, s...)
+	copy(b[i+len(s):], b[i:n])
+	copy(b[i:], s)
+	return b
+}
 
-		k := p + a.Key
-		if _, ok := m[k]; !ok {
-			keys = append(keys, k)
-		}
-		m[k] = a.Value.Any()
-	}
+func appendColorStart(b []byte, code string) []byte {
+	return append(b, code...)
+}
 
-	return keys
+func appendColorEnd(b []byte, code string) []byte {
+	if code == "" {
+		return b
+	}
+	return append(b, ansi.Reset...)
 }
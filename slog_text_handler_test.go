@@ -0,0 +1,67 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestSlogTextFields(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		option   logger.SlogTextOption
+		expected string
+	}{
+		{
+			name:     "sorted",
+			option:   logger.SlogTextOption{DisableTimestamp: true},
+			expected: "level=INFO msg=message a=3 b=2 c=5 d=\"with space\" e=1.5 z=4\n",
+		},
+		{
+			name:     "insertion order",
+			option:   logger.SlogTextOption{DisableTimestamp: true, DisableSorting: true},
+			expected: "level=INFO msg=message z=4 b=2 a=3 d=\"with space\" c=5 e=1.5\n",
+		},
+		{
+			name:     "formatted",
+			option:   logger.SlogTextOption{DisableTimestamp: true, ForceFormatting: true, SpacePadding: 10},
+			expected: " INFO message    a=3 b=2 c=5 d=with space e=1.5 z=4\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			l := slog.New(logger.NewSlogTextHandler(w, &tc.option)).With("z", 1, "b", 2).With("a", 1, "z", 4)
+
+			l.Info("message", "a", 2, "d", "with space", "c", 5, "a", 3, "e", 1.5)
+			if w.String() != tc.expected {
+				t.Errorf("\n   got: %q\nexpect: %q", w, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSlogTextClock(t *testing.T) {
+	calls := 0
+	clock := func() time.Time {
+		calls++
+		return logger.DeterministicTime.Add(time.Duration(calls) * 2 * time.Second)
+	}
+
+	w := new(bytes.Buffer)
+	h := logger.NewSlogTextHandler(w, &logger.SlogTextOption{ForceFormatting: true, DisableColors: true, Clock: clock})
+	if calls != 0 {
+		t.Fatalf("building the handler must not call the clock, got %d calls", calls)
+	}
+
+	l := slog.New(h)
+	l.Info("first")
+	l.With("k", "v").Info("second") // The clones share the first record's time.
+
+	expected := "[0000]  INFO first\n[0002]  INFO second k=v\n"
+	if w.String() != expected {
+		t.Errorf("\n   got: %q\nexpect: %q", w, expected)
+	}
+}
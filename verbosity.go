@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	vmoduleSpec struct {
+		patterns []vmodulePattern
+		// Cache of the matched pattern per caller's program counter.
+		cache sync.Map
+	}
+
+	// vmoduleMatch is the result of matching a caller's file against the patterns.
+	// Without match, the global verbosity is read on each call so SetVerbosity still applies.
+	vmoduleMatch struct {
+		threshold int
+		matched   bool
+	}
+
+	vmodulePattern struct {
+		pattern string
+		depth   int // Number of trailing path elements matched.
+		level   int
+	}
+)
+
+var (
+	verbosity atomic.Int32
+	vmodule   atomic.Pointer[vmoduleSpec]
+)
+
+// LevelV returns the slog level used for the verbosity v, i.e. slog.LevelDebug - v.
+// Such levels are rendered as `V<v>' by the handlers of this package.
+func LevelV(v int) slog.Level {
+	return slog.LevelDebug - slog.Level(v)
+}
+
+// SetVerbosity sets the global verbosity threshold (glog's -v).
+// A V(n) logger logs when n is lower or equal than the threshold.
+func SetVerbosity(v int) {
+	verbosity.Store(int32(v))
+}
+
+// Verbosity returns the global verbosity threshold.
+func Verbosity() int {
+	return int(verbosity.Load())
+}
+
+// SetVModule sets the per-source-file verbosity thresholds (glog's -vmodule), overriding the global one.
+// The spec is a comma-separated list of pattern=N where the pattern is a glob matched against
+// the source file's base name without the `.go' extension, e.g. `server=2,gfs*=3`.
+// A pattern containing `/' is matched against as many trailing path elements, e.g. `http/server=2`.
+func SetVModule(spec string) error {
+	var patterns []vmodulePattern
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		pattern, value, ok := strings.Cut(entry, "=")
+		if !ok || pattern == "" {
+			return fmt.Errorf("invalid vmodule entry: %s", entry)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid vmodule pattern %q: %w", pattern, err)
+		}
+
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid vmodule level %q: %w", entry, err)
+		}
+
+		patterns = append(patterns, vmodulePattern{
+			pattern: pattern,
+			depth:   strings.Count(pattern, "/") + 1,
+			level:   level,
+		})
+	}
+
+	vmodule.Store(&vmoduleSpec{patterns: patterns})
+	return nil
+}
+
+// RegisterVerbosityFlags registers the `-v' and `-vmodule' flags on the given flag set.
+func RegisterVerbosityFlags(fs *flag.FlagSet) {
+	fs.Var(verbosityFlag{}, "v", "log level for V logs")
+	fs.Var(vmoduleFlag{}, "vmodule", "comma-separated list of pattern=N settings for file-filtered V logs")
+}
+
+// verbose reports whether the verbosity v is enabled for the caller at the given depth.
+func verbose(v int, depth int) bool {
+	spec := vmodule.Load()
+	if spec == nil || len(spec.patterns) == 0 {
+		return v <= Verbosity()
+	}
+
+	pc, file, _, ok := runtime.Caller(depth + 1)
+	if !ok {
+		return v <= Verbosity()
+	}
+
+	if m, ok := spec.cache.Load(pc); ok {
+		return v <= m.(vmoduleMatch).level()
+	}
+
+	var m vmoduleMatch
+	file = strings.TrimSuffix(filepath.ToSlash(file), ".go")
+	for _, p := range spec.patterns {
+		name := file
+		for i, n := len(file)-1, 0; i >= 0; i-- {
+			if file[i] == '/' {
+				n++
+				if n == p.depth {
+					name = file[i+1:]
+					break
+				}
+			}
+		}
+
+		if ok, _ := path.Match(p.pattern, name); ok {
+			m = vmoduleMatch{threshold: p.level, matched: true}
+			break
+		}
+	}
+
+	spec.cache.Store(pc, m)
+	return v <= m.level()
+}
+
+// level returns the matched pattern's threshold or the current global verbosity.
+func (m vmoduleMatch) level() int {
+	if m.matched {
+		return m.threshold
+	}
+	return Verbosity()
+}
+
+type verbosityFlag struct{}
+
+func (verbosityFlag) String() string {
+	return strconv.Itoa(Verbosity())
+}
+
+func (verbosityFlag) Set(value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	SetVerbosity(v)
+	return nil
+}
+
+type vmoduleFlag struct{}
+
+func (vmoduleFlag) String() string {
+	spec := vmodule.Load()
+	if spec == nil {
+		return ""
+	}
+
+	entries := make([]string, 0, len(spec.patterns))
+	for _, p := range spec.patterns {
+		entries = append(entries, fmt.Sprintf("%s=%d", p.pattern, p.level))
+	}
+	return strings.Join(entries, ",")
+}
+
+func (vmoduleFlag) Set(value string) error {
+	return SetVModule(value)
+}
@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+func TestVerbosity(t *testing.T) {
+	defer logger.SetVerbosity(0)
+	defer logger.SetVModule("")
+
+	w := new(bytes.Buffer)
+	lvl := new(slog.LevelVar)
+	lvl.Set(logger.LevelV(9))
+	l := logger.WrapSlogHandler(logger.NewSlogTextHandler(w, &logger.SlogTextOption{Level: lvl, DisableTimestamp: true}))
+
+	l.V(0).Info("v0")
+	l.V(1).Info("v1") // Disabled
+	logger.SetVerbosity(2)
+	l.V(2).WithField("k", "v").Infof("v%d", 2)
+	l.V(3).Info("v3") // Disabled
+	l.V(2).Warn("warn")
+	l.V(3).Warn("warn") // Disabled
+
+	expected := strings.Join([]string{
+		`level=DEBUG msg=v0`,
+		`level=V2 msg=v2 k=v`,
+		`level=WARN msg=warn`,
+		"",
+	}, "\n")
+	if w.String() != expected {
+		t.Errorf("\n   got:\n%s\nexpect:\n%s", w, expected)
+	}
+
+	//
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	logger.RegisterVerbosityFlags(fs)
+	if err := fs.Parse([]string{"-v", "1", "-vmodule", "nope=9,verbosity_*=4"}); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Verbosity() != 1 || fs.Lookup("vmodule").Value.String() != "nope=9,verbosity_*=4" {
+		t.Errorf("got: %d %s", logger.Verbosity(), fs.Lookup("vmodule").Value)
+	}
+
+	w.Reset()
+	l.V(4).Info("v4")
+	l.V(5).Info("v5") // Disabled
+	if w.String() != "level=TRACE msg=v4\n" {
+		t.Errorf("got: %s", w)
+	}
+
+	if err := logger.SetVModule("*/verbosity_test=5"); err != nil {
+		t.Fatal(err)
+	}
+	w.Reset()
+	l.V(5).Info("v5")
+	if w.String() != "level=V5 msg=v5\n" {
+		t.Errorf("got: %s", w)
+	}
+
+	// Without matching pattern, the global verbosity applies even after the call site is cached.
+	if err := logger.SetVModule("nomatch=5"); err != nil {
+		t.Fatal(err)
+	}
+	w.Reset()
+	for _, v := range []int{0, 3} {
+		logger.SetVerbosity(v)
+		l.V(2).Info("v2")
+	}
+	if w.String() != "level=V2 msg=v2\n" {
+		t.Errorf("got: %s", w)
+	}
+
+	if err := logger.SetVModule("nope"); err == nil {
+		t.Error("an error is expected")
+	}
+
+	// The handler's level applies too.
+	lvl.Set(slog.LevelInfo)
+	w.Reset()
+	l.V(0).Info("v0")
+	if w.String() != "" {
+		t.Errorf("the handler's level must be checked, got: %s", w)
+	}
+}
+
+func TestVerbosityGELF(t *testing.T) {
+	defer logger.SetVerbosity(0)
+	logger.SetVerbosity(2)
+
+	w := new(bytes.Buffer)
+	l := logger.WrapSlogHandler(logger.NewSlogGELFHandler(w, &logger.SlogGELFOption{Hostname: "hostname-42", Level: logger.LevelV(2)}))
+	l.V(2).Info("v2")
+
+	expected := regexp.MustCompile(`"level":7,"short_message":"v2","_level_name":"V2"\}`)
+	if !expected.MatchString(w.String()) {
+		t.Errorf("got: %s", w)
+	}
+}
+
+func TestVerbosityCompliance(t *testing.T) {
+	var _ logger.Logger = logger.NewNullLogger().V(1)
+}